@@ -1502,6 +1502,10 @@ end, { desc = "Previous diagnostic" })
 vim.keymap.set("n", "<Leader>dt", ":Trouble diagnostics toggle<CR>", { desc = "Toggle trouble diagnostics" })
 vim.keymap.set("n", "<Leader>dd", ":Trouble diagnostics toggle filter.buf=0<CR>", { desc = "Buffer diagnostics" })
 
+-- Slugs (requires tools/slug on the PATH)
+vim.keymap.set("n", "<Leader>-", ":.!slug -s 2>/dev/null<CR>", { desc = "Slugify line" })
+vim.keymap.set("v", "<Leader>-", ":!slug -s 2>/dev/null<CR>", { desc = "Slugify selected lines" })
+
 -- Claude Code integration
 vim.keymap.set("n", "<Leader>cc", ":ClaudeCodeDiagnostic<CR>", { desc = "Send diagnostic to Claude Code" })
 
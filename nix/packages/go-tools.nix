@@ -26,7 +26,7 @@ buildGoModule {
 
   # Vendor hash - update after changing go.mod dependencies
   # Run: nix build .#dotfiles-tools 2>&1 | grep "got:" to get the correct hash
-  vendorHash = "sha256-jyaLM8O8Z0wA5NJgTEyPPXhnmoNbvKxOYFES1eUfVDo=";
+  vendorHash = "sha256-1csNgJBcfuuXcS8k+mPLvxek47oSLSqYi3IzJVxoaD4=";
 
   # Build all tools as subpackages
   subPackages = [
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/t-eckert/dotfiles/tools/serve/server"
+)
+
+// config holds every setting of the server.
+type config struct {
+	ConfigFile string `json:"configFile,omitempty"`
+	Profile    string `json:"profile,omitempty"`
+
+	Bind string `json:"bind"`
+	Port int    `json:"port"`
+	QR   bool   `json:"qr"`
+
+	TLS       bool   `json:"tls"`
+	TLSCert   string `json:"tlsCert"`
+	TLSKey    string `json:"tlsKey"`
+	H2C       bool   `json:"h2c"`
+	AccessLog bool   `json:"accessLog"`
+	Preload   bool   `json:"preload"`
+	Admin     bool   `json:"admin"`
+
+	Auth     string        `json:"auth,omitempty"`
+	Token    bool          `json:"token"`
+	TokenTTL time.Duration `json:"tokenTTL"`
+
+	Compress      bool     `json:"compress"`
+	CompressMin   int64    `json:"compressMin"`
+	CompressTypes []string `json:"compressTypes"`
+	Precompressed bool     `json:"precompressed"`
+
+	Cache            string   `json:"cache"`
+	CacheRules       []string `json:"cacheRules"`
+	ImmutablePattern string   `json:"immutablePattern"`
+	ETag             bool     `json:"etag"`
+
+	Headers         []string      `json:"headers"`
+	CORS            []string      `json:"cors"`
+	CORSCredentials bool          `json:"corsCredentials"`
+	CORSMaxAge      time.Duration `json:"corsMaxAge"`
+	Isolation       string        `json:"isolation"`
+
+	Throttle     string   `json:"throttle"`
+	ThrottleConn string   `json:"throttleConn"`
+	Latency      []string `json:"latency"`
+	Faults       []string `json:"faults"`
+	FaultsFile   string   `json:"faultsFile"`
+
+	Mounts           []string `json:"mounts"`
+	All              bool     `json:"all"`
+	Markdown         bool     `json:"markdown"`
+	MarkdownIndex    bool     `json:"markdownIndex"`
+	Archives         bool     `json:"archives"`
+	Mock             string   `json:"mock"`
+	Proxies          []string `json:"proxies"`
+	ProxyCredentials bool     `json:"proxyCredentials"`
+
+	WebDAV      bool `json:"webdav"`
+	WebDAVWrite bool `json:"webdavWrite"`
+
+	ReadHeaderTimeout time.Duration `json:"readHeaderTimeout"`
+	ReadTimeout       time.Duration `json:"readTimeout"`
+	WriteTimeout      time.Duration `json:"writeTimeout"`
+	IdleTimeout       time.Duration `json:"idleTimeout"`
+	ShutdownTimeout   time.Duration `json:"shutdownTimeout"`
+}
+
+// defaultConfig returns the settings used when no flags are given.
+func defaultConfig() config {
+	return config{
+		Port:              8080,
+		AccessLog:         true,
+		CompressMin:       1024,
+		CompressTypes:     server.DefaultCompressTypes,
+		Precompressed:     true,
+		Markdown:          true,
+		Archives:          true,
+		Cache:             server.CacheDefault,
+		ImmutablePattern:  server.DefaultImmutablePattern,
+		CORSMaxAge:        10 * time.Minute,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		WriteTimeout:      10 * time.Minute,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   10 * time.Second,
+	}
+}
+
+// newFlagSet returns the command-line flags, bound to the fields of cfg.
+func newFlagSet(cfg *config) *flag.FlagSet {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+
+	fs.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "read settings from this file instead of serve.yaml or .serve.json")
+	fs.StringVar(&cfg.Profile, "profile", cfg.Profile, "use the named profile from the config file")
+	fs.StringVar(&cfg.Profile, "p", cfg.Profile, "shorthand for --profile")
+
+	fs.StringVar(&cfg.Bind, "bind", cfg.Bind, "define what address to bind to (default all interfaces)")
+	fs.IntVar(&cfg.Port, "port", cfg.Port, "define what TCP port to bind to (0 picks a free port)")
+	fs.BoolVar(&cfg.QR, "qr", cfg.QR, "print a QR code of the LAN URL for opening on a phone")
+
+	fs.BoolVar(&cfg.TLS, "tls", cfg.TLS, "serve HTTPS and HTTP/2 with a generated self-signed certificate")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "serve HTTPS and HTTP/2 with this certificate file")
+	fs.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "private key file for --tls-cert")
+	fs.BoolVar(&cfg.H2C, "h2c", cfg.H2C, "accept HTTP/2 without TLS from clients with prior knowledge")
+	fs.BoolVar(&cfg.AccessLog, "access-log", cfg.AccessLog, "log every request to stderr")
+	fs.BoolVar(&cfg.Admin, "admin", cfg.Admin, "serve metrics, a health check and the configuration under /_serve/")
+	fs.BoolVar(&cfg.Preload, "preload", cfg.Preload, "add Link preload headers for the stylesheets and scripts of HTML pages")
+
+	fs.StringVar(&cfg.Auth, "auth", cfg.Auth, "require HTTP basic auth with the given user:pass")
+	fs.BoolVar(&cfg.Token, "token", cfg.Token, "require a random access token as a query parameter or cookie")
+	fs.DurationVar(&cfg.TokenTTL, "token-ttl", cfg.TokenTTL, "expire the access token after this duration (0 never expires)")
+
+	fs.BoolVar(&cfg.Compress, "compress", cfg.Compress, "compress responses on the fly with brotli, zstd or gzip")
+	fs.Int64Var(&cfg.CompressMin, "compress-min", cfg.CompressMin, "smallest response in bytes to compress on the fly")
+	fs.Var((*commaList)(&cfg.CompressTypes), "compress-types", "comma-separated content types to compress on the fly")
+	fs.BoolVar(&cfg.Precompressed, "precompressed", cfg.Precompressed, "serve precompressed siblings such as app.js.br and app.js.gz")
+
+	fs.StringVar(&cfg.Cache, "cache", cfg.Cache, "cache mode: default, no-store, no-cache or immutable")
+	fs.Var((*stringList)(&cfg.CacheRules), "cache-rule", "set Cache-Control for paths matching a glob, e.g. '*.html=no-cache' (repeatable)")
+	fs.StringVar(&cfg.ImmutablePattern, "immutable-pattern", cfg.ImmutablePattern, "regular expression matching hashed file names in immutable mode")
+	fs.BoolVar(&cfg.ETag, "etag", cfg.ETag, "send strong ETags based on a hash of the file contents")
+
+	fs.Var((*stringList)(&cfg.Headers), "header", "add a 'Name: value' header to every response (repeatable)")
+	fs.Var((*commaList)(&cfg.CORS), "cors", "comma-separated origins allowed to make cross-origin requests, or *")
+	fs.BoolVar(&cfg.CORSCredentials, "cors-credentials", cfg.CORSCredentials, "allow cookies and HTTP auth on cross-origin requests")
+	fs.DurationVar(&cfg.CORSMaxAge, "cors-max-age", cfg.CORSMaxAge, "how long browsers may cache preflight responses")
+	fs.StringVar(&cfg.Isolation, "isolation", cfg.Isolation, "cross-origin isolation preset: require-corp or credentialless")
+
+	fs.StringVar(&cfg.Throttle, "throttle", cfg.Throttle, "limit total bandwidth, e.g. 500KB/s")
+	fs.StringVar(&cfg.ThrottleConn, "throttle-conn", cfg.ThrottleConn, "limit the bandwidth of each connection, e.g. 100KB/s")
+	fs.Var((*stringList)(&cfg.Latency), "latency", "delay responses for paths matching a glob, e.g. '/api/*=200ms' (repeatable)")
+
+	fs.Var((*stringList)(&cfg.Faults), "fault", "inject failures for paths matching a glob, e.g. '/api/*=status:503,p:0.3' (repeatable)")
+	fs.StringVar(&cfg.FaultsFile, "faults", cfg.FaultsFile, "read fault injection rules from a JSON file")
+
+	fs.Var((*stringList)(&cfg.Mounts), "mount", "serve a directory under a URL prefix, e.g. '/docs=./site' (repeatable)")
+	fs.BoolVar(&cfg.All, "all", cfg.All, "serve dotfiles and paths matched by .gitignore and .serveignore")
+	fs.BoolVar(&cfg.Markdown, "markdown", cfg.Markdown, "render Markdown files as HTML for browsers")
+	fs.BoolVar(&cfg.MarkdownIndex, "markdown-index", cfg.MarkdownIndex, "show a directory's README.md or index.md instead of its listing")
+	fs.BoolVar(&cfg.Archives, "archives", cfg.Archives, "allow downloading directories with ?download=zip or ?download=tar.gz")
+	fs.Var((*stringList)(&cfg.Proxies), "proxy", "forward requests under a URL prefix to another server, e.g. '/api=http://localhost:3000' (repeatable)")
+	fs.BoolVar(&cfg.ProxyCredentials, "proxy-credentials", cfg.ProxyCredentials, "forward serve's basic auth and access token to proxied servers")
+	fs.BoolVar(&cfg.WebDAV, "webdav", cfg.WebDAV, "let WebDAV clients browse the served files read-only")
+	fs.BoolVar(&cfg.WebDAVWrite, "webdav-write", cfg.WebDAVWrite, "let WebDAV clients change the served files (implies --webdav)")
+	fs.StringVar(&cfg.Mock, "mock", cfg.Mock, "answer requests matching fixtures in this directory, e.g. ./fixtures")
+
+	fs.DurationVar(&cfg.ReadHeaderTimeout, "read-header-timeout", cfg.ReadHeaderTimeout, "maximum time to read request headers")
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "maximum time to read a whole request")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "maximum time to write a response (0 disables)")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "how long to keep idle connections open")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "how long to wait for in-flight requests on shutdown")
+
+	return fs
+}
+
+// parseFlags returns the configuration given by the command-line arguments
+// and the config file, if there is one. Flags on the command line override
+// the file.
+func parseFlags(args []string) (config, error) {
+	cli := defaultConfig()
+	cliFlags := newFlagSet(&cli)
+	if err := cliFlags.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	file := cli.ConfigFile
+	if file == "" {
+		file = findConfigFile()
+	}
+	if file == "" {
+		if cli.Profile != "" {
+			err := fmt.Errorf("profile %q requested but there is no config file", cli.Profile)
+			fmt.Fprintln(cliFlags.Output(), err)
+			return config{}, err
+		}
+		return cli, nil
+	}
+
+	onCommandLine := map[string]bool{}
+	cliFlags.Visit(func(f *flag.Flag) { onCommandLine[f.Name] = true })
+
+	cfg := defaultConfig()
+	fs := newFlagSet(&cfg)
+	settings, err := loadConfigFile(file, cli.Profile)
+	if err == nil {
+		err = applySettings(fs, settings, func(name string) bool { return onCommandLine[name] })
+		if err != nil {
+			err = fmt.Errorf("error in %s: %w", file, err)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		return config{}, err
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+	cfg.ConfigFile = file
+	return cfg, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/t-eckert/dotfiles/tools/serve/server"
+)
+
+func TestParseFlagsDefaults(t *testing.T) {
+	cfg, err := parseFlags(nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaultConfig(), cfg)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.True(t, cfg.Precompressed)
+	assert.Equal(t, 10*time.Second, cfg.ShutdownTimeout)
+}
+
+func TestParseFlags(t *testing.T) {
+	cfg, err := parseFlags([]string{
+		"--port", "3000",
+		"--bind", "127.0.0.1",
+		"--header", "X-A: 1",
+		"--header", "X-B: 2",
+		"--cors", "https://a.example, https://b.example",
+		"--compress-types", "text/css",
+		"--write-timeout", "30s",
+		"--all",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3000, cfg.Port)
+	assert.Equal(t, "127.0.0.1", cfg.Bind)
+	assert.Equal(t, []string{"X-A: 1", "X-B: 2"}, cfg.Headers)
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.CORS)
+	assert.Equal(t, []string{"text/css"}, cfg.CompressTypes)
+	assert.Equal(t, 30*time.Second, cfg.WriteTimeout)
+	assert.True(t, cfg.All)
+
+	// Defaults are not mutated by parsing
+	assert.Equal(t, server.DefaultCompressTypes, defaultConfig().CompressTypes)
+	assert.Len(t, server.DefaultCompressTypes, 7)
+}
+
+func TestParseFlagsInvalid(t *testing.T) {
+	_, err := parseFlags([]string{"--port", "not-a-number"})
+	assert.Error(t, err)
+
+	_, err = parseFlags([]string{"--no-such-flag"})
+	assert.Error(t, err)
+}
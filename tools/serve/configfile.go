@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/t-eckert/dotfiles/tools/serve/server"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames are looked for in the served directory, in order. The
+// file server hides them.
+var configFileNames = server.ConfigFileNames
+
+// userConfigFileNames are looked for in $XDG_CONFIG_HOME/serve when the
+// served directory has no config file.
+var userConfigFileNames = []string{"serve.yaml", "serve.yml", "serve.json"}
+
+// findConfigFile returns the config file for the current directory, or the
+// empty string if there is none.
+func findConfigFile() string {
+	for _, name := range configFileNames {
+		if isFile(name) {
+			return name
+		}
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range userConfigFileNames {
+		if file := filepath.Join(dir, "serve", name); isFile(file) {
+			return file
+		}
+	}
+	return ""
+}
+
+func isFile(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// loadConfigFile reads the settings of a config file. Settings are keyed by
+// flag name. The named profile, if any, overrides the settings at the top of
+// the file.
+func loadConfigFile(file, profile string) (map[string]any, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, so one parser handles both formats.
+	var settings map[string]any
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", file, err)
+	}
+	if settings == nil {
+		settings = map[string]any{}
+	}
+
+	profiles := map[string]any{}
+	if p, ok := settings["profiles"]; ok {
+		profiles, ok = p.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("error in %s: profiles must map names to settings", file)
+		}
+		delete(settings, "profiles")
+	}
+
+	if profile == "" {
+		return settings, nil
+	}
+	p, ok := profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("no profile %q in %s, available profiles: %s", profile, file, strings.Join(names, ", "))
+	}
+	overrides, ok := p.(map[string]any)
+	if !ok && p != nil {
+		return nil, fmt.Errorf("error in %s: profile %q must map flag names to values", file, profile)
+	}
+	for name, value := range overrides {
+		settings[name] = value
+	}
+	return settings, nil
+}
+
+// applySettings sets the flags named by the settings. Flags for which skip
+// returns true are left alone so that the command line can set them.
+func applySettings(fs *flag.FlagSet, settings map[string]any, skip func(name string) bool) error {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil || name == "config" || name == "profile" || name == "p" {
+			return fmt.Errorf("unknown setting %q", name)
+		}
+		if skip(name) {
+			continue
+		}
+
+		values, err := settingValues(settings[name])
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		switch f.Value.(type) {
+		case *stringList:
+		case *commaList:
+			values = []string{strings.Join(values, ",")}
+		default:
+			if len(values) != 1 {
+				return fmt.Errorf("invalid %s: expected a single value", name)
+			}
+		}
+
+		for _, v := range values {
+			if err := fs.Set(name, v); err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// settingValues converts a setting into flag values. Lists become one value
+// per element.
+func settingValues(v any) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case bool:
+		return []string{strconv.FormatBool(v)}, nil
+	case int, int64, uint64, float64:
+		return []string{fmt.Sprint(v)}, nil
+	case []any:
+		var values []string
+		for _, item := range v {
+			if _, ok := item.([]any); ok {
+				return nil, errors.New("lists cannot be nested")
+			}
+			itemValues, err := settingValues(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, itemValues...)
+		}
+		return values, nil
+	case nil:
+		return nil, errors.New("missing value")
+	}
+	return nil, fmt.Errorf("expected a value or a list, got %T", v)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigYAML = `
+port: 3000
+header:
+  - "X-Team: web"
+cors: [https://a.example, https://b.example]
+write-timeout: 30s
+profiles:
+  spa-dev:
+    port: 5173
+    mount: ["/=dist"]
+    proxy: ["/api=http://localhost:8000"]
+    auth: dev:secret
+  empty:
+`
+
+// inConfigDir runs the test from a fresh directory holding the files, with
+// an empty user config directory.
+func inConfigDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := writeTree(t, files)
+	t.Chdir(dir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	return dir
+}
+
+func TestParseFlagsConfigFile(t *testing.T) {
+	inConfigDir(t, map[string]string{"serve.yaml": testConfigYAML})
+
+	cfg, err := parseFlags(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "serve.yaml", cfg.ConfigFile)
+	assert.Equal(t, 3000, cfg.Port)
+	assert.Equal(t, []string{"X-Team: web"}, cfg.Headers)
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.CORS)
+	assert.Equal(t, 30*time.Second, cfg.WriteTimeout)
+	assert.Empty(t, cfg.Mounts)
+
+	cfg, err = parseFlags([]string{"-p", "spa-dev"})
+	require.NoError(t, err)
+	assert.Equal(t, 5173, cfg.Port)
+	assert.Equal(t, []string{"/=dist"}, cfg.Mounts)
+	assert.Equal(t, []string{"/api=http://localhost:8000"}, cfg.Proxies)
+	assert.Equal(t, "dev:secret", cfg.Auth)
+	assert.Equal(t, []string{"X-Team: web"}, cfg.Headers)
+
+	// The command line wins, and lists replace those in the file.
+	cfg, err = parseFlags([]string{"--profile", "spa-dev", "--port", "9000", "--mount", "/=build"})
+	require.NoError(t, err)
+	assert.Equal(t, 9000, cfg.Port)
+	assert.Equal(t, []string{"/=build"}, cfg.Mounts)
+
+	cfg, err = parseFlags([]string{"-p", "empty"})
+	require.NoError(t, err)
+	assert.Equal(t, 3000, cfg.Port)
+
+	_, err = parseFlags([]string{"-p", "missing"})
+	assert.ErrorContains(t, err, "empty, spa-dev")
+}
+
+func TestParseFlagsConfigFileLocations(t *testing.T) {
+	dir := inConfigDir(t, map[string]string{".serve.json": `{"port": 4000, "all": true}`})
+
+	cfg, err := parseFlags(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4000, cfg.Port)
+	assert.True(t, cfg.All)
+
+	// The user config directory is used when the served directory has none.
+	require.NoError(t, os.Remove(filepath.Join(dir, ".serve.json")))
+	userDir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "serve")
+	require.NoError(t, os.MkdirAll(userDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "serve.yaml"), []byte("port: 4001\n"), 0644))
+	cfg, err = parseFlags(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4001, cfg.Port)
+
+	// An explicit file wins over both.
+	other := filepath.Join(dir, "other.yaml")
+	require.NoError(t, os.WriteFile(other, []byte("port: 4002\n"), 0644))
+	cfg, err = parseFlags([]string{"--config", other})
+	require.NoError(t, err)
+	assert.Equal(t, 4002, cfg.Port)
+	assert.Equal(t, other, cfg.ConfigFile)
+}
+
+func TestParseFlagsConfigFileInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown setting": "colour: blue\n",
+		"invalid value":   "port: high\n",
+		"list for value":  "port: [1, 2]\n",
+		"nested list":     "header: [[a]]\n",
+		"map value":       "mount: {docs: site}\n",
+		"meta setting":    "profile: spa-dev\n",
+		"bad profiles":    "profiles: [a]\n",
+		"bad yaml":        "port: [\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			inConfigDir(t, map[string]string{"serve.yaml": content})
+			_, err := parseFlags(nil)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("profile without file", func(t *testing.T) {
+		inConfigDir(t, map[string]string{})
+		_, err := parseFlags([]string{"-p", "spa-dev"})
+		assert.Error(t, err)
+	})
+}
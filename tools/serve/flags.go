@@ -0,0 +1,32 @@
+package main
+
+import "strings"
+
+// stringList is a flag which may be repeated to collect several values.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// commaList is a flag holding a comma-separated list of values.
+type commaList []string
+
+func (c *commaList) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *commaList) Set(value string) error {
+	*c = nil
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*c = append(*c, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/t-eckert/dotfiles/tools/serve/server"
+)
+
+// buildHandler builds the handler described by the configuration. The access
+// token and metrics are passed in so that they survive reloads.
+func buildHandler(cfg config, tok *server.AccessToken, stats *server.Metrics) (http.Handler, error) {
+	opts, err := handlerOptions(cfg, tok, stats)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return server.NewHandler(opts), nil
+}
+
+// handlerOptions parses the configuration into handler options.
+func handlerOptions(cfg config, tok *server.AccessToken, stats *server.Metrics) (server.Options, error) {
+	opts := server.Options{
+		All:              cfg.All,
+		ContentETags:     cfg.ETag,
+		Precompressed:    cfg.Precompressed,
+		Preload:          cfg.Preload,
+		Markdown:         cfg.Markdown,
+		MarkdownIndex:    cfg.MarkdownIndex,
+		Archives:         cfg.Archives,
+		MockDir:          cfg.Mock,
+		WriteTimeout:     cfg.WriteTimeout,
+		ProxyCredentials: cfg.ProxyCredentials,
+		Token:            tok,
+	}
+
+	for _, m := range cfg.Mounts {
+		parsed, err := server.ParseMount(m)
+		if err != nil {
+			return server.Options{}, err
+		}
+		opts.Mounts = append(opts.Mounts, parsed)
+	}
+
+	for _, p := range cfg.Proxies {
+		route, err := server.ParseProxy(p)
+		if err != nil {
+			return server.Options{}, err
+		}
+		opts.Proxies = append(opts.Proxies, route)
+	}
+
+	if cfg.Compress {
+		opts.Compress = &server.CompressOptions{
+			MinSize: cfg.CompressMin,
+			Types:   cfg.CompressTypes,
+		}
+	}
+
+	if cfg.Throttle != "" {
+		rate, err := server.ParseRate(cfg.Throttle)
+		if err != nil {
+			return server.Options{}, err
+		}
+		opts.Throttle.Global = server.NewRateLimiter(rate)
+	}
+	if cfg.ThrottleConn != "" {
+		rate, err := server.ParseRate(cfg.ThrottleConn)
+		if err != nil {
+			return server.Options{}, err
+		}
+		opts.Throttle.PerConn = rate
+	}
+
+	for _, l := range cfg.Latency {
+		rule, err := server.ParseLatencyRule(l)
+		if err != nil {
+			return server.Options{}, err
+		}
+		opts.Latency = append(opts.Latency, rule)
+	}
+
+	if cfg.FaultsFile != "" {
+		rules, err := server.LoadFaultRules(cfg.FaultsFile)
+		if err != nil {
+			return server.Options{}, err
+		}
+		opts.Faults = append(opts.Faults, rules...)
+	}
+	for _, f := range cfg.Faults {
+		rule, err := server.ParseFaultRule(f)
+		if err != nil {
+			return server.Options{}, err
+		}
+		opts.Faults = append(opts.Faults, rule)
+	}
+
+	if !server.ValidCacheMode(cfg.Cache) {
+		return server.Options{}, fmt.Errorf("unknown cache mode %q", cfg.Cache)
+	}
+	opts.Cache.Mode = cfg.Cache
+	if cfg.Cache == server.CacheImmutable {
+		re, err := regexp.Compile(cfg.ImmutablePattern)
+		if err != nil {
+			return server.Options{}, fmt.Errorf("invalid immutable pattern: %w", err)
+		}
+		opts.Cache.Immutable = re
+	}
+	for _, r := range cfg.CacheRules {
+		rule, err := server.ParseCacheRule(r)
+		if err != nil {
+			return server.Options{}, err
+		}
+		opts.Cache.Rules = append(opts.Cache.Rules, rule)
+	}
+
+	if cfg.Admin {
+		opts.Admin = &server.AdminOptions{Metrics: stats, Config: redactConfig(cfg)}
+	}
+	if cfg.WebDAV || cfg.WebDAVWrite {
+		opts.WebDAV = &server.WebDAVOptions{Write: cfg.WebDAVWrite}
+	}
+
+	if cfg.Auth != "" {
+		user, pass, err := server.ParseCredentials(cfg.Auth)
+		if err != nil {
+			return server.Options{}, err
+		}
+		opts.User, opts.Password = user, pass
+	}
+
+	if len(cfg.CORS) > 0 {
+		opts.CORS = &server.CORSOptions{
+			Origins:     cfg.CORS,
+			Credentials: cfg.CORSCredentials,
+			MaxAge:      cfg.CORSMaxAge,
+		}
+	}
+
+	opts.Headers = http.Header{}
+	if cfg.Isolation != "" {
+		preset, ok := server.IsolationPresets[cfg.Isolation]
+		if !ok {
+			return server.Options{}, fmt.Errorf("unknown isolation preset %q", cfg.Isolation)
+		}
+		for name, values := range preset {
+			opts.Headers[name] = values
+		}
+	}
+	userHeaders := http.Header{}
+	for _, h := range cfg.Headers {
+		name, value, err := server.ParseHeader(h)
+		if err != nil {
+			return server.Options{}, err
+		}
+		userHeaders.Add(name, value)
+	}
+	for name, values := range userHeaders {
+		opts.Headers[name] = values
+	}
+
+	if cfg.AccessLog {
+		opts.AccessLog = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	return opts, nil
+}
+
+// redactConfig hides the password in the configuration.
+func redactConfig(cfg config) config {
+	if user, _, ok := strings.Cut(cfg.Auth, ":"); ok {
+		cfg.Auth = user + ":****"
+	}
+	return cfg
+}
+
+// swappableHandler serves requests with a handler that can be replaced while
+// the server is running.
+type swappableHandler struct {
+	current atomic.Pointer[http.Handler]
+}
+
+func newSwappableHandler(h http.Handler) *swappableHandler {
+	s := &swappableHandler{}
+	s.Store(h)
+	return s
+}
+
+// Store replaces the handler used for new requests.
+func (s *swappableHandler) Store(h http.Handler) {
+	s.current.Store(&h)
+}
+
+func (s *swappableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.current.Load()).ServeHTTP(w, r)
+}
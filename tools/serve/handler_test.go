@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/t-eckert/dotfiles/tools/serve/server"
+)
+
+// writeTree creates the files, keyed by slash-separated path, in a new
+// temporary directory and returns it.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestBuildHandler(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"index.txt": "hello",
+		".env":      "SECRET=1",
+	})
+	oldDir, _ := os.Getwd()
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(oldDir)
+
+	cfg := defaultConfig()
+	cfg.AccessLog = false
+	cfg.Cache = server.CacheNoStore
+	cfg.Headers = []string{"X-Served-By: serve"}
+	handler, err := buildHandler(cfg, nil, nil)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index.txt", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello", rec.Body.String())
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "serve", rec.Header().Get("X-Served-By"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.env", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestBuildHandlerInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config)
+	}{
+		{"cache mode", func(c *config) { c.Cache = "forever" }},
+		{"immutable pattern", func(c *config) { c.Cache = server.CacheImmutable; c.ImmutablePattern = "[" }},
+		{"cache rule", func(c *config) { c.CacheRules = []string{"nope"} }},
+		{"auth", func(c *config) { c.Auth = "nocolon" }},
+		{"isolation", func(c *config) { c.Isolation = "strict" }},
+		{"header", func(c *config) { c.Headers = []string{"nocolon"} }},
+		{"duplicate mount", func(c *config) { c.Mounts = []string{"/docs=.", "/docs=."} }},
+		{"mock fixtures", func(c *config) { c.Mock = "no-such-fixtures" }},
+		{"proxy", func(c *config) { c.Proxies = []string{"/api=localhost"} }},
+		{"throttle", func(c *config) { c.Throttle = "fast" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			tt.modify(&cfg)
+			_, err := buildHandler(cfg, nil, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSwappableHandler(t *testing.T) {
+	respond := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		})
+	}
+
+	s := newSwappableHandler(respond("first"))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "first", rec.Body.String())
+
+	s.Store(respond("second"))
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "second", rec.Body.String())
+}
+
+func TestBuildHandlerAdminConfig(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AccessLog = false
+	cfg.Admin = true
+	cfg.Auth = "admin:hunter2"
+	handler, err := buildHandler(cfg, nil, nil)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/_serve/config", nil)
+	req.SetBasicAuth("admin", "hunter2")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got config
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "admin:****", got.Auth)
+	assert.Equal(t, cfg.Port, got.Port)
+	assert.NotContains(t, rec.Body.String(), "hunter2")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// maxPortAttempts is how many consecutive ports are tried when the requested
+// port is already in use.
+const maxPortAttempts = 100
+
+// listen binds to the given host and port. If the port is taken, the next free
+// port is used instead. A port of 0 lets the operating system pick one.
+func listen(host string, port int) (net.Listener, error) {
+	for attempt := 0; attempt < maxPortAttempts; attempt++ {
+		ln, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port+attempt)))
+		if err == nil {
+			return ln, nil
+		}
+		if port == 0 || !errors.Is(err, syscall.EADDRINUSE) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("no free port in range %d-%d", port, port+maxPortAttempts-1)
+}
+
+// reachableURLs returns the URLs at which a server bound to host:port can be
+// reached. Wildcard binds list localhost followed by every LAN address.
+func reachableURLs(host string, port int) []string {
+	p := strconv.Itoa(port)
+
+	ip := net.ParseIP(host)
+	if host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return []string{"http://" + net.JoinHostPort(host, p) + "/"}
+	}
+
+	urls := []string{"http://" + net.JoinHostPort("localhost", p) + "/"}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return urls
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		// An IPv4 bind can only be reached over IPv4.
+		if ip != nil && ip.To4() != nil && ipNet.IP.To4() == nil {
+			continue
+		}
+		urls = append(urls, "http://"+net.JoinHostPort(ipNet.IP.String(), p)+"/")
+	}
+	return urls
+}
+
+// qrCode renders the content as a QR code made of block characters that can
+// be scanned from a terminal.
+func qrCode(content string) (string, error) {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return "", err
+	}
+	return q.ToSmallString(false), nil
+}
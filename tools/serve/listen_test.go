@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenEphemeralPort(t *testing.T) {
+	ln, err := listen("127.0.0.1", 0)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	assert.NotZero(t, ln.Addr().(*net.TCPAddr).Port)
+}
+
+func TestListenFallsBackToNextFreePort(t *testing.T) {
+	taken, err := listen("127.0.0.1", 0)
+	require.NoError(t, err)
+	defer taken.Close()
+	port := taken.Addr().(*net.TCPAddr).Port
+
+	ln, err := listen("127.0.0.1", port)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	assert.Greater(t, ln.Addr().(*net.TCPAddr).Port, port)
+}
+
+func TestListenInvalidHost(t *testing.T) {
+	_, err := listen("not a host", 0)
+	assert.Error(t, err)
+}
+
+func TestReachableURLsSpecificHost(t *testing.T) {
+	assert.Equal(t, []string{"http://127.0.0.1:8080/"}, reachableURLs("127.0.0.1", 8080))
+	assert.Equal(t, []string{"http://[::1]:3000/"}, reachableURLs("::1", 3000))
+	assert.Equal(t, []string{"http://devbox:80/"}, reachableURLs("devbox", 80))
+}
+
+func TestReachableURLsWildcard(t *testing.T) {
+	for _, host := range []string{"", "0.0.0.0", "::"} {
+		t.Run(host, func(t *testing.T) {
+			urls := reachableURLs(host, 8080)
+			require.NotEmpty(t, urls)
+			assert.Equal(t, "http://localhost:8080/", urls[0])
+			for _, u := range urls {
+				assert.True(t, strings.HasSuffix(u, ":8080/"), u)
+				assert.NotContains(t, u, "127.0.0.1")
+			}
+		})
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	code, err := qrCode("http://192.168.1.5:8080/")
+	require.NoError(t, err)
+	assert.NotEmpty(t, code)
+	assert.Greater(t, strings.Count(code, "\n"), 10)
+}
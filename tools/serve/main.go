@@ -1,25 +1,198 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/t-eckert/dotfiles/tools/serve/server"
 )
 
 func main() {
-	var port string
-	flag.StringVar(&port, "port", "8080", "define what TCP port to bind to")
-	flag.Parse()
+	cfg, err := parseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	var accessTok *server.AccessToken
+	if cfg.Token {
+		accessTok, err = server.NewAccessToken(cfg.TokenTTL)
+		if err != nil {
+			log.Fatalf("Error creating access token: %s\n", err)
+		}
+	}
 
-	fs := http.FileServer(http.Dir("."))
-	http.Handle("/", fs)
+	stats := server.NewMetrics()
+	handler, err := buildHandler(cfg, accessTok, stats)
+	if err != nil {
+		log.Fatalf("Error configuring server: %s\n", err)
+	}
+	swappable := newSwappableHandler(handler)
 
-	addr := fmt.Sprintf(":%s", port)
+	tlsCfg, err := tlsConfig(cfg)
+	if err != nil {
+		log.Fatalf("Error configuring TLS: %s\n", err)
+	}
 
-	fmt.Printf("Serving current directory on HTTP port: %s\n", port)
-	err := http.ListenAndServe(addr, nil)
+	ln, err := listen(cfg.Bind, cfg.Port)
 	if err != nil {
 		log.Fatalf("Error starting server: %s\n", err)
 	}
+	actualPort := ln.Addr().(*net.TCPAddr).Port
+	if cfg.Port != 0 && actualPort != cfg.Port {
+		fmt.Printf("Port %d is in use, using %d instead\n", cfg.Port, actualPort)
+	}
+
+	urls := reachableURLs(cfg.Bind, actualPort)
+	if tlsCfg != nil {
+		for i := range urls {
+			urls[i] = "https" + strings.TrimPrefix(urls[i], "http")
+		}
+	}
+	if accessTok != nil {
+		for i := range urls {
+			urls[i] += "?token=" + accessTok.Value
+		}
+	}
+
+	if cfg.ConfigFile != "" {
+		if cfg.Profile != "" {
+			fmt.Printf("Using profile %s from %s\n", cfg.Profile, cfg.ConfigFile)
+		} else {
+			fmt.Printf("Using settings from %s\n", cfg.ConfigFile)
+		}
+	}
+	if len(cfg.Mounts) == 0 {
+		fmt.Println("Serving current directory on:")
+	} else {
+		for _, m := range cfg.Mounts {
+			prefix, dir, _ := strings.Cut(m, "=")
+			fmt.Printf("Mounting %s at %s\n", dir, server.CleanPrefix(prefix))
+		}
+		fmt.Println("Serving on:")
+	}
+	for _, u := range urls {
+		fmt.Printf("  %s\n", u)
+	}
+	if accessTok != nil && !accessTok.Expires.IsZero() {
+		fmt.Printf("Token expires at %s\n", accessTok.Expires.Format(time.Kitchen))
+	}
+
+	if cfg.QR {
+		code, err := qrCode(urls[len(urls)-1])
+		if err != nil {
+			log.Fatalf("Error rendering QR code: %s\n", err)
+		}
+		fmt.Print(code)
+	}
+
+	srv := newServer(cfg, swappable)
+	srv.TLSConfig = tlsCfg
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if tlsCfg != nil {
+			serveErr <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		serveErr <- srv.Serve(ln)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	for {
+		select {
+		case err := <-serveErr:
+			if !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Error starting server: %s\n", err)
+			}
+			return
+
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				newCfg, err := parseFlags(os.Args[1:])
+				if err != nil {
+					fmt.Println("Error reloading configuration, keeping the old one")
+					continue
+				}
+				if needsRestart(cfg, newCfg) {
+					fmt.Println("Changes to the address, TLS, tokens or timeouts take effect after a restart")
+				}
+				handler, err := buildHandler(newCfg, accessTok, stats)
+				if err != nil {
+					fmt.Printf("Error reloading configuration, keeping the old one: %s\n", err)
+					continue
+				}
+				swappable.Store(handler)
+				fmt.Println("Reloaded configuration")
+				continue
+			}
+
+			fmt.Println("Shutting down, waiting for in-flight requests...")
+			if err := shutdown(srv, cfg.ShutdownTimeout, signals); err != nil {
+				fmt.Printf("Forced shutdown: %s\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+}
+
+// newServer returns an HTTP server with timeouts that keep slow clients from
+// holding connections open forever.
+func newServer(cfg config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ConnContext:       server.ConnContext,
+		Protocols:         protocols(cfg.H2C),
+	}
+}
+
+// needsRestart reports whether the settings read on reload differ in ways
+// that only apply when the server starts.
+func needsRestart(before, after config) bool {
+	return before.Bind != after.Bind || before.Port != after.Port ||
+		before.TLS != after.TLS || before.TLSCert != after.TLSCert || before.TLSKey != after.TLSKey || before.H2C != after.H2C ||
+		before.Token != after.Token || before.TokenTTL != after.TokenTTL ||
+		before.ReadHeaderTimeout != after.ReadHeaderTimeout || before.ReadTimeout != after.ReadTimeout ||
+		before.WriteTimeout != after.WriteTimeout || before.IdleTimeout != after.IdleTimeout
+}
+
+// shutdown stops accepting connections and waits for in-flight requests to
+// finish. Connections are closed forcibly after the timeout or when another
+// signal arrives.
+func shutdown(srv *http.Server, timeout time.Duration, signals <-chan os.Signal) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close()
+		return err
+	}
+	return nil
 }
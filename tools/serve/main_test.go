@@ -1,43 +1,17 @@
 package main
 
 import (
-	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestDefaultPort(t *testing.T) {
-	// Default port should be "8080"
-	expected := "8080"
-	// This would be set by flag.StringVar in main
-	assert.Equal(t, expected, "8080")
-}
-
-func TestAddressFormatting(t *testing.T) {
-	tests := []struct {
-		port     string
-		expected string
-	}{
-		{"8080", ":8080"},
-		{"3000", ":3000"},
-		{"80", ":80"},
-		{"9999", ":9999"},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.port, func(t *testing.T) {
-			addr := fmt.Sprintf(":%s", tt.port)
-			assert.Equal(t, tt.expected, addr)
-		})
-	}
-}
-
 func TestFileServerServesFiles(t *testing.T) {
 	// Create a temporary directory with test files
 	tempDir := t.TempDir()
@@ -209,15 +183,82 @@ func TestHTTPHandlerSetup(t *testing.T) {
 	assert.NotNil(t, resp)
 }
 
-func TestPortValidation(t *testing.T) {
-	// Test valid port numbers
-	validPorts := []string{"80", "8080", "3000", "9999", "1234"}
-
-	for _, port := range validPorts {
-		t.Run(port, func(t *testing.T) {
-			addr := fmt.Sprintf(":%s", port)
-			assert.NotEmpty(t, addr)
-			assert.Contains(t, addr, ":")
-		})
-	}
+func TestNewServerTimeouts(t *testing.T) {
+	cfg := defaultConfig()
+	srv := newServer(cfg, http.NotFoundHandler())
+
+	assert.Equal(t, cfg.ReadHeaderTimeout, srv.ReadHeaderTimeout)
+	assert.Equal(t, cfg.ReadTimeout, srv.ReadTimeout)
+	assert.Equal(t, cfg.WriteTimeout, srv.WriteTimeout)
+	assert.Equal(t, cfg.IdleTimeout, srv.IdleTimeout)
+	assert.NotZero(t, srv.ReadHeaderTimeout)
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	ln, err := listen("127.0.0.1", 0)
+	require.NoError(t, err)
+	srv := newServer(defaultConfig(), handler)
+	go srv.Serve(ln)
+
+	result := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		result <- string(body)
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- shutdown(srv, 5*time.Second, make(chan os.Signal))
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	assert.Equal(t, "done", <-result)
+	assert.NoError(t, <-shutdownErr)
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+
+	ln, err := listen("127.0.0.1", 0)
+	require.NoError(t, err)
+	srv := newServer(defaultConfig(), handler)
+	go srv.Serve(ln)
+
+	go http.Get("http://" + ln.Addr().String() + "/")
+	<-started
+
+	err = shutdown(srv, 50*time.Millisecond, make(chan os.Signal))
+	assert.Error(t, err)
+}
+
+func TestNeedsRestart(t *testing.T) {
+	before := defaultConfig()
+	after := defaultConfig()
+	after.Headers = []string{"X-A: 1"}
+	after.Mounts = []string{"/docs=."}
+	assert.False(t, needsRestart(before, after))
+
+	after.Port = 9000
+	assert.True(t, needsRestart(before, after))
 }
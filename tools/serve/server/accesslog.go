@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// accessLog wraps the handler so that every request is logged with the
+// negotiated protocol, the status, the size of the body and how long it took,
+// for example:
+//
+//	127.0.0.1:50312 "GET /app.js HTTP/2.0" 200 1532 1.204ms
+//
+// The access token is left out of logged URLs.
+func accessLog(next http.Handler, logger *log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := &loggingWriter{ResponseWriter: w}
+		defer func() {
+			status := lw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			logger.Printf("%s %q %d %d %s", r.RemoteAddr, r.Method+" "+withoutToken(r.URL)+" "+r.Proto, status, lw.bytes, time.Since(start).Round(time.Microsecond))
+		}()
+		next.ServeHTTP(lw, r)
+	})
+}
+
+// withoutToken returns the request URI of u with the token query parameter
+// removed.
+func withoutToken(u *url.URL) string {
+	query := u.Query()
+	if !query.Has(tokenParam) {
+		return u.RequestURI()
+	}
+	query.Del(tokenParam)
+	stripped := *u
+	stripped.RawQuery = query.Encode()
+	return stripped.RequestURI()
+}
+
+// loggingWriter records the status code and the number of body bytes written.
+type loggingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (lw *loggingWriter) WriteHeader(status int) {
+	// Informational responses such as 103 Early Hints come before the real one.
+	if lw.status == 0 && status >= http.StatusOK {
+		lw.status = status
+	}
+	lw.ResponseWriter.WriteHeader(status)
+}
+
+func (lw *loggingWriter) Write(b []byte) (int, error) {
+	if lw.status == 0 {
+		lw.status = http.StatusOK
+	}
+	n, err := lw.ResponseWriter.Write(b)
+	lw.bytes += int64(n)
+	return n, err
+}
+
+func (lw *loggingWriter) Flush() {
+	if f, ok := lw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (lw *loggingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(lw.ResponseWriter).Hijack()
+}
+
+func (lw *loggingWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	handler := accessLog(okHandler, log.New(&buf, "", 0))
+
+	req := httptest.NewRequest(http.MethodGet, "/app.js?v=1", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Proto = "HTTP/2.0"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Regexp(t, `^192\.0\.2\.1:1234 "GET /app\.js\?v=1 HTTP/2\.0" 200 2 \S+\n$`, buf.String())
+
+	buf.Reset()
+	handler = accessLog(http.NotFoundHandler(), log.New(&buf, "", 0))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, "/missing", nil))
+	assert.Contains(t, buf.String(), `"HEAD /missing HTTP/1.1" 404`)
+}
+
+func TestAccessLogLeavesOutToken(t *testing.T) {
+	var buf bytes.Buffer
+	handler := accessLog(okHandler, log.New(&buf, "", 0))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/app.js?v=1&token=s3cret", nil))
+	assert.Contains(t, buf.String(), `"GET /app.js?v=1 HTTP/1.1" 200`)
+	assert.NotContains(t, buf.String(), "s3cret")
+
+	buf.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?token=s3cret", nil))
+	assert.Contains(t, buf.String(), `"GET / HTTP/1.1" 200`)
+	assert.NotContains(t, buf.String(), "s3cret")
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// adminPrefix is the URL namespace of the admin endpoints. It shadows any
+// file or directory of the same name.
+const adminPrefix = "/_serve/"
+
+// AdminOptions controls the endpoints under /_serve/.
+type AdminOptions struct {
+	// Metrics are published at /_serve/metrics. The handler records requests
+	// into them.
+	Metrics *Metrics
+	// Config is published as JSON at /_serve/config, so it should not hold
+	// secrets.
+	Config any
+}
+
+// adminRoutes wraps the handler so that requests under /_serve/ are answered
+// by the admin endpoints: Prometheus metrics, a health check and the
+// configuration.
+func adminRoutes(next http.Handler, opts AdminOptions) http.Handler {
+	stats := opts.Metrics
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+adminPrefix+"metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		stats.writePrometheus(w)
+	})
+	mux.HandleFunc("GET "+adminPrefix+"health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"status": "ok",
+			"uptime": time.Since(stats.started).Round(time.Second).String(),
+		})
+	})
+	mux.HandleFunc("GET "+adminPrefix+"config", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, opts.Config)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, adminPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Admin responses describe the live server and must never be cached.
+		w.Header().Set("Cache-Control", "no-store")
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
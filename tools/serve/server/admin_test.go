@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	handler := adminRoutes(okHandler, AdminOptions{
+		Metrics: NewMetrics(),
+		Config:  map[string]any{"port": 8080},
+	})
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/_serve/health")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Body.String(), `"status": "ok"`)
+
+	rec = get("/_serve/config")
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, map[string]any{"port": 8080.0}, got)
+
+	rec = get("/_serve/metrics")
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, rec.Body.String(), "serve_uptime_seconds")
+
+	assert.Equal(t, http.StatusNotFound, get("/_serve/nope").Code)
+	assert.Equal(t, "ok", get("/index.html").Body.String())
+}
+
+func TestAdminRequiresAuth(t *testing.T) {
+	dir := writeTree(t, map[string]string{"index.txt": "hello"})
+	stats := NewMetrics()
+	handler := NewHandler(Options{
+		Mounts:   []Mount{{Prefix: "/", Dir: dir}},
+		Admin:    &AdminOptions{Metrics: stats},
+		User:     "admin",
+		Password: "hunter2",
+	})
+
+	for _, target := range []string{"/_serve/config", "/index.txt"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, target)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/_serve/metrics", nil)
+	req.SetBasicAuth("admin", "hunter2")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	// Rejected requests are counted too, but admin requests are not.
+	assert.Contains(t, rec.Body.String(), `serve_requests_total{status="401",path="/index.txt"} 1`)
+	assert.NotContains(t, rec.Body.String(), `path="/_serve/`)
+}
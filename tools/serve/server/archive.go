@@ -0,0 +1,246 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// archiveFormats maps the ?download= values to their file extensions and
+// content types.
+var archiveFormats = map[string]struct {
+	ext         string
+	contentType string
+}{
+	"zip":    {".zip", "application/zip"},
+	"tar.gz": {".tar.gz", "application/gzip"},
+}
+
+// archiveDownloads wraps the handler so that ?download=zip or
+// ?download=tar.gz on a directory streams an archive of its contents.
+// Directory listings gain links to both. rootName names the archive of the
+// root directory. markdownIndex tells whether Markdown indexes replace
+// listings. Every write of an archive gets writeTimeout to finish in.
+func archiveDownloads(next http.Handler, root http.FileSystem, rootName string, markdownIndex bool, writeTimeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		dir := r.URL.Path
+		if !strings.HasSuffix(dir, "/") || !isDir(root, dir) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		format := r.URL.Query().Get("download")
+		if format == "" {
+			serveListingWithDownloads(next, root, markdownIndex, w, r)
+			return
+		}
+
+		archive, ok := archiveFormats[format]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown archive format %q", format), http.StatusBadRequest)
+			return
+		}
+
+		name := path.Base(dir)
+		if dir == "/" {
+			name = rootName
+		}
+		w.Header().Set("Content-Type", archive.contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + archive.ext}))
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		out := &deadlineWriter{w: w, timeout: writeTimeout}
+		var err error
+		switch format {
+		case "zip":
+			err = writeZip(out, root, dir)
+		case "tar.gz":
+			err = writeTarGz(out, root, dir)
+		}
+		if err != nil {
+			// The headers are gone, so all that is left is to cut the
+			// response short so the client sees a broken download.
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+// serveListingWithDownloads appends archive links to the directory listing.
+// Directories with an index page are left alone.
+func serveListingWithDownloads(next http.Handler, root http.FileSystem, markdownIndex bool, w http.ResponseWriter, r *http.Request) {
+	indexes := []string{"index.html"}
+	if markdownIndex {
+		indexes = append(indexes, markdownIndexes...)
+	}
+	for _, index := range indexes {
+		if f, err := root.Open(path.Join(r.URL.Path, index)); err == nil {
+			f.Close()
+			next.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	listing := false
+	next.ServeHTTP(&hookWriter{ResponseWriter: w, before: func(status int) {
+		listing = status == http.StatusOK && strings.HasPrefix(w.Header().Get("Content-Type"), "text/html")
+	}}, r)
+	if listing && r.Method == http.MethodGet {
+		io.WriteString(w, `<p>Download: <a href="?download=zip">zip</a> &middot; <a href="?download=tar.gz">tar.gz</a></p>`+"\n")
+	}
+}
+
+// isDir reports whether the name is a directory in the file system.
+func isDir(root http.FileSystem, name string) bool {
+	f, err := root.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	return err == nil && info.IsDir()
+}
+
+// walkFunc is called for every file and directory below the walked root.
+// rel is the slash-separated path relative to the root.
+type walkFunc func(rel string, info fs.FileInfo, f http.File) error
+
+// walk visits the tree below dir depth-first. Symlinked directories are
+// skipped so that loops cannot make the walk run forever.
+func walk(root http.FileSystem, dir, rel string, fn walkFunc) error {
+	d, err := root.Open(dir)
+	if err != nil {
+		return err
+	}
+	entries, err := d.Readdir(-1)
+	d.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		entryRel := path.Join(rel, entry.Name())
+
+		f, err := root.Open(name)
+		if err != nil {
+			// Hidden or vanished since the listing.
+			continue
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		if info.IsDir() {
+			f.Close()
+			if entry.Mode()&fs.ModeSymlink != 0 {
+				continue
+			}
+			if err := fn(entryRel, info, nil); err != nil {
+				return err
+			}
+			if err := walk(root, name, entryRel, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !info.Mode().IsRegular() {
+			f.Close()
+			continue
+		}
+		err = fn(entryRel, info, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deadlineWriter extends the write deadline of the response before every
+// write.
+type deadlineWriter struct {
+	w       http.ResponseWriter
+	timeout time.Duration
+}
+
+func (dw *deadlineWriter) Write(b []byte) (int, error) {
+	extendWriteDeadline(dw.w, dw.timeout)
+	return dw.w.Write(b)
+}
+
+// writeZip streams a zip archive of the directory to w.
+func writeZip(w io.Writer, root http.FileSystem, dir string) error {
+	zw := zip.NewWriter(w)
+	err := walk(root, dir, "", func(rel string, info fs.FileInfo, f http.File) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		if info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+
+		entry, err := zw.CreateHeader(header)
+		if err != nil || f == nil {
+			return err
+		}
+		_, err = io.Copy(entry, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeTarGz streams a gzipped tar archive of the directory to w.
+func writeTarGz(w io.Writer, root http.FileSystem, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := walk(root, dir, "", func(rel string, info fs.FileInfo, f http.File) error {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		// Owner names leak details about the machine and mean nothing elsewhere.
+		header.Uname, header.Gname = "", ""
+
+		if err := tw.WriteHeader(header); err != nil || f == nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func archiveTree(t *testing.T) http.FileSystem {
+	t.Helper()
+	dir := writeTree(t, map[string]string{
+		".env":             "SECRET=1",
+		".gitignore":       "*.log\n",
+		"debug.log":        "log",
+		"index.txt":        "root file",
+		"docs/guide.txt":   "guide",
+		"docs/deep/a.txt":  "deep",
+		"docs/.hidden.txt": "hidden",
+	})
+	return newHiddenFS(http.Dir(dir))
+}
+
+func download(t *testing.T, handler http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestArchiveZip(t *testing.T) {
+	root := archiveTree(t)
+	handler := archiveDownloads(http.FileServer(root), root, "project", false, 0)
+
+	rec := download(t, handler, "/?download=zip")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=project.zip`, rec.Header().Get("Content-Disposition"))
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	require.NoError(t, err)
+
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			contents[f.Name] = ""
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		contents[f.Name] = string(b)
+	}
+
+	assert.Equal(t, map[string]string{
+		"index.txt":       "root file",
+		"docs/":           "",
+		"docs/guide.txt":  "guide",
+		"docs/deep/":      "",
+		"docs/deep/a.txt": "deep",
+	}, contents)
+}
+
+func TestArchiveTarGzSubdirectory(t *testing.T) {
+	root := archiveTree(t)
+	handler := archiveDownloads(http.FileServer(root), root, "project", false, 0)
+
+	rec := download(t, handler, "/docs/?download=tar.gz")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/gzip", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=docs.tar.gz`, rec.Header().Get("Content-Disposition"))
+
+	gz, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+		assert.Empty(t, header.Uname)
+	}
+	assert.ElementsMatch(t, []string{"guide.txt", "deep/", "deep/a.txt"}, names)
+}
+
+func TestArchiveErrors(t *testing.T) {
+	root := archiveTree(t)
+	handler := archiveDownloads(http.FileServer(root), root, "project", false, 0)
+
+	assert.Equal(t, http.StatusBadRequest, download(t, handler, "/docs/?download=rar").Code)
+
+	// Files are served normally
+	rec := download(t, handler, "/index.txt?download=zip")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "root file", rec.Body.String())
+
+	// Hidden directories cannot be archived
+	dir := writeTree(t, map[string]string{".git/config": "[core]"})
+	hidden := newHiddenFS(http.Dir(dir))
+	rec = download(t, archiveDownloads(http.FileServer(hidden), hidden, "x", false, 0), "/.git/?download=zip")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestArchiveListingLinks(t *testing.T) {
+	root := archiveTree(t)
+	handler := archiveDownloads(http.FileServer(root), root, "project", false, 0)
+
+	rec := download(t, handler, "/docs/")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "guide.txt")
+	assert.Contains(t, rec.Body.String(), `<a href="?download=zip">zip</a>`)
+	assert.Contains(t, rec.Body.String(), `<a href="?download=tar.gz">tar.gz</a>`)
+}
+
+func TestArchiveListingLinksBesideMarkdownIndex(t *testing.T) {
+	dir := writeTree(t, map[string]string{"docs/README.md": "# Docs\n"})
+	root := http.Dir(dir)
+
+	rec := download(t, archiveDownloads(http.FileServer(root), root, "project", false, 0), "/docs/")
+	assert.Contains(t, rec.Body.String(), `<a href="?download=zip">zip</a>`)
+
+	rec = download(t, archiveDownloads(http.FileServer(root), root, "project", true, 0), "/docs/")
+	assert.NotContains(t, rec.Body.String(), "?download=zip")
+}
+
+func TestArchiveSkipsSymlinkedDirectories(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a/file.txt": "a"})
+	if err := os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "a", "loop")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	root := http.Dir(dir)
+
+	var names []string
+	err := walk(root, "/", "", func(rel string, _ os.FileInfo, _ http.File) error {
+		names = append(names, rel)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "a/file.txt"}, names)
+}
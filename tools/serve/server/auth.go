@@ -0,0 +1,102 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const tokenCookie = "serve_token"
+
+// tokenParam is the query parameter which carries the token in links.
+const tokenParam = "token"
+
+// basicAuth wraps the handler so that every request must present the given
+// credentials using HTTP basic auth.
+func basicAuth(next http.Handler, user, pass string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok || !secureEqual(u, user) || !secureEqual(p, pass) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="serve", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ParseCredentials splits a user:pass flag value.
+func ParseCredentials(s string) (string, string, error) {
+	user, pass, ok := strings.Cut(s, ":")
+	if !ok || user == "" {
+		return "", "", fmt.Errorf("credentials must be in the form user:pass, got %q", s)
+	}
+	return user, pass, nil
+}
+
+// AccessToken is a random secret that clients must present to reach the server.
+type AccessToken struct {
+	Value string
+	// Expires is zero for tokens which never expire.
+	Expires time.Time
+}
+
+// NewAccessToken generates a token which expires after ttl. A zero ttl never expires.
+func NewAccessToken(ttl time.Duration) (*AccessToken, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	t := &AccessToken{Value: hex.EncodeToString(b)}
+	if ttl > 0 {
+		t.Expires = time.Now().Add(ttl)
+	}
+	return t, nil
+}
+
+// expired reports whether the token is no longer valid at the given time.
+func (t *AccessToken) expired(now time.Time) bool {
+	return !t.Expires.IsZero() && now.After(t.Expires)
+}
+
+// middleware wraps the handler so that requests must carry the token in the
+// `token` query parameter or the token cookie. A valid query parameter sets
+// the cookie so that links followed from the page keep working.
+func (t *AccessToken) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.expired(time.Now()) {
+			http.Error(w, "Access token has expired", http.StatusForbidden)
+			return
+		}
+
+		if q := r.URL.Query().Get(tokenParam); q != "" && secureEqual(q, t.Value) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     tokenCookie,
+				Value:    t.Value,
+				Path:     "/",
+				Expires:  t.Expires,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c, err := r.Cookie(tokenCookie); err == nil && secureEqual(c.Value, t.Value) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	})
+}
+
+// secureEqual compares two strings in constant time.
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+func TestBasicAuth(t *testing.T) {
+	handler := basicAuth(okHandler, "alice", "secret")
+
+	tests := []struct {
+		name     string
+		user     string
+		pass     string
+		setAuth  bool
+		expected int
+	}{
+		{"no credentials", "", "", false, http.StatusUnauthorized},
+		{"wrong password", "alice", "nope", true, http.StatusUnauthorized},
+		{"wrong user", "bob", "secret", true, http.StatusUnauthorized},
+		{"valid", "alice", "secret", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.setAuth {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expected, rec.Code)
+			if tt.expected == http.StatusUnauthorized {
+				assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Basic")
+			}
+		})
+	}
+}
+
+func TestParseCredentials(t *testing.T) {
+	user, pass, err := ParseCredentials("alice:pa:ss")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user)
+	assert.Equal(t, "pa:ss", pass)
+
+	_, _, err = ParseCredentials("alice")
+	assert.Error(t, err)
+
+	_, _, err = ParseCredentials(":secret")
+	assert.Error(t, err)
+}
+
+func TestAccessTokenQueryAndCookie(t *testing.T) {
+	tok, err := NewAccessToken(0)
+	require.NoError(t, err)
+	assert.Len(t, tok.Value, 32)
+	handler := tok.middleware(okHandler)
+
+	// Without a token the request is rejected
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// A wrong token is rejected
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token=wrong", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// The query parameter grants access and sets a cookie
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token="+tok.Value, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, tokenCookie, cookies[0].Name)
+
+	// The cookie alone grants access
+	req := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAccessTokenExpiry(t *testing.T) {
+	tok, err := NewAccessToken(time.Minute)
+	require.NoError(t, err)
+	assert.False(t, tok.expired(time.Now()))
+	assert.True(t, tok.expired(time.Now().Add(2*time.Minute)))
+
+	tok.Expires = time.Now().Add(-time.Second)
+	rec := httptest.NewRecorder()
+	tok.middleware(okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token="+tok.Value, nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestAccessTokenNeverExpires(t *testing.T) {
+	tok, err := NewAccessToken(0)
+	require.NoError(t, err)
+	assert.True(t, tok.Expires.IsZero())
+	assert.False(t, tok.expired(time.Now().Add(24*365*time.Hour)))
+}
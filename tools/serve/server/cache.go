@@ -0,0 +1,201 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache modes accepted by --cache.
+const (
+	CacheDefault   = "default"
+	CacheNoStore   = "no-store"
+	CacheNoCache   = "no-cache"
+	CacheImmutable = "immutable"
+)
+
+// DefaultImmutablePattern matches file names carrying a content hash, such as
+// app.3f9a1c2b.js or index-4b1e7d0a9c.css.
+const DefaultImmutablePattern = `[.-][0-9a-fA-F]{8,}\.[[:alnum:]]+$`
+
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// CacheRule sets the Cache-Control header for paths matching a glob.
+type CacheRule struct {
+	pattern string
+	value   string
+}
+
+// ParseCacheRule parses a glob=value flag such as "*.html=no-cache".
+func ParseCacheRule(s string) (CacheRule, error) {
+	pattern, value, ok := strings.Cut(s, "=")
+	pattern, value = strings.TrimSpace(pattern), strings.TrimSpace(value)
+	if !ok || pattern == "" || value == "" {
+		return CacheRule{}, fmt.Errorf("cache rule must be in the form glob=value, got %q", s)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return CacheRule{}, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return CacheRule{pattern: pattern, value: value}, nil
+}
+
+// CachePolicy decides the Cache-Control header for each path.
+type CachePolicy struct {
+	// Mode is one of the cache mode constants.
+	Mode string
+	// Immutable matches hashed assets in immutable mode.
+	Immutable *regexp.Regexp
+	// Rules are checked in order before the mode and the first match wins.
+	Rules []CacheRule
+}
+
+// ValidCacheMode reports whether the mode is accepted by --cache.
+func ValidCacheMode(mode string) bool {
+	switch mode {
+	case CacheDefault, CacheNoStore, CacheNoCache, CacheImmutable:
+		return true
+	}
+	return false
+}
+
+// cacheControl returns the Cache-Control value for the URL path, or the empty
+// string to leave the header unset.
+func (p CachePolicy) cacheControl(urlPath string) string {
+	for _, rule := range p.Rules {
+		if globMatch(rule.pattern, urlPath) {
+			return rule.value
+		}
+	}
+
+	switch p.Mode {
+	case CacheNoStore:
+		return "no-store"
+	case CacheNoCache:
+		return "no-cache"
+	case CacheImmutable:
+		if p.Immutable != nil && p.Immutable.MatchString(path.Base(urlPath)) {
+			return immutableCacheControl
+		}
+		return "no-cache"
+	}
+	return ""
+}
+
+// globMatch matches a glob against the URL path. Globs without a slash are
+// matched against the file name alone.
+func globMatch(pattern, urlPath string) bool {
+	if !strings.Contains(pattern, "/") {
+		urlPath = path.Base(urlPath)
+	}
+	ok, _ := path.Match(pattern, urlPath)
+	return ok
+}
+
+// withCachePolicy wraps the handler so that successful responses carry the
+// Cache-Control header chosen by the policy. Errors are never cached.
+func withCachePolicy(next http.Handler, policy CachePolicy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := policy.cacheControl(r.URL.Path)
+		if value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		hw := &hookWriter{ResponseWriter: w, before: func(status int) {
+			if status < http.StatusBadRequest {
+				w.Header().Set("Cache-Control", value)
+			}
+		}}
+		next.ServeHTTP(hw, r)
+		hw.finish()
+	})
+}
+
+// contentETags computes strong ETags from file contents. Hashes are cached
+// until the file's size or modification time changes.
+type contentETags struct {
+	root    http.FileSystem
+	mu      sync.Mutex
+	entries map[string]etagEntry
+}
+
+type etagEntry struct {
+	modTime time.Time
+	size    int64
+	tag     string
+}
+
+func newContentETags(root http.FileSystem) *contentETags {
+	return &contentETags{root: root, entries: map[string]etagEntry{}}
+}
+
+// etag returns the strong ETag for the named file, or false if it is not a
+// regular file.
+func (c *contentETags) etag(name string) (string, bool) {
+	f, err := c.root.Open(name)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.tag, true
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", false
+	}
+	tag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+
+	c.mu.Lock()
+	c.entries[name] = etagEntry{modTime: info.ModTime(), size: info.Size(), tag: tag}
+	c.mu.Unlock()
+	return tag, true
+}
+
+// middleware sets the ETag header before the file server runs, which lets it
+// answer If-None-Match and If-Range requests.
+func (c *contentETags) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			if tag, ok := c.etag(r.URL.Path); ok {
+				w.Header().Set("ETag", tag)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// modTimeETags wraps the handler so that files get a strong ETag built from
+// their modification time and size. Unlike Last-Modified it has nanosecond
+// precision, which keeps If-Range from resuming a download against a file
+// that changed within the same second.
+func modTimeETags(next http.Handler, root http.FileSystem) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			if f, err := root.Open(r.URL.Path); err == nil {
+				if info, err := f.Stat(); err == nil && !info.IsDir() {
+					w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+				}
+				f.Close()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
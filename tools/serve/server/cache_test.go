@@ -0,0 +1,150 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheRule(t *testing.T) {
+	rule, err := ParseCacheRule("*.html = no-cache")
+	require.NoError(t, err)
+	assert.Equal(t, CacheRule{pattern: "*.html", value: "no-cache"}, rule)
+
+	for _, invalid := range []string{"*.html", "=no-cache", "*.html=", "[=x"} {
+		_, err := ParseCacheRule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestValidCacheMode(t *testing.T) {
+	for _, mode := range []string{"default", "no-store", "no-cache", "immutable"} {
+		assert.True(t, ValidCacheMode(mode), mode)
+	}
+	assert.False(t, ValidCacheMode("forever"))
+}
+
+func TestCachePolicy(t *testing.T) {
+	immutable := regexp.MustCompile(DefaultImmutablePattern)
+
+	tests := []struct {
+		name     string
+		policy   CachePolicy
+		path     string
+		expected string
+	}{
+		{"default leaves header unset", CachePolicy{Mode: CacheDefault}, "/app.js", ""},
+		{"no-store", CachePolicy{Mode: CacheNoStore}, "/app.js", "no-store"},
+		{"no-cache", CachePolicy{Mode: CacheNoCache}, "/app.js", "no-cache"},
+		{"immutable hashed asset", CachePolicy{Mode: CacheImmutable, Immutable: immutable}, "/assets/app.3f9a1c2b.js", immutableCacheControl},
+		{"immutable dash hash", CachePolicy{Mode: CacheImmutable, Immutable: immutable}, "/index-4b1e7d0a9c.css", immutableCacheControl},
+		{"immutable unhashed asset", CachePolicy{Mode: CacheImmutable, Immutable: immutable}, "/index.html", "no-cache"},
+		{"rule on file name", CachePolicy{Mode: CacheNoStore, Rules: []CacheRule{{"*.woff2", "max-age=600"}}}, "/fonts/a.woff2", "max-age=600"},
+		{"rule on full path", CachePolicy{Rules: []CacheRule{{"/static/*", "max-age=60"}}}, "/static/x.png", "max-age=60"},
+		{"rule does not match nested path", CachePolicy{Rules: []CacheRule{{"/static/*", "max-age=60"}}}, "/static/img/x.png", ""},
+		{"first rule wins", CachePolicy{Rules: []CacheRule{{"*.js", "a"}, {"app.js", "b"}}}, "/app.js", "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.policy.cacheControl(tt.path))
+		})
+	}
+}
+
+func TestWithCachePolicy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "page.html"), []byte("<p>hi</p>"), 0644))
+
+	handler := withCachePolicy(http.FileServer(http.Dir(dir)), CachePolicy{Mode: CacheImmutable})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/page.html", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+
+	// Errors must not be cached
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing.html", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Header().Get("Cache-Control"))
+
+	// Handlers which write nothing still send 200 OK
+	handler = withCachePolicy(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), CachePolicy{Mode: CacheNoStore})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/", nil))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+}
+
+func TestContentETags(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.js")
+	require.NoError(t, os.WriteFile(file, []byte("console.log(1)"), 0644))
+
+	etags := newContentETags(http.Dir(dir))
+	handler := etags.middleware(http.FileServer(http.Dir(dir)))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	tag := rec.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, tag)
+
+	// A matching If-None-Match is answered with 304
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("If-None-Match", tag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// Changing the content changes the ETag
+	require.NoError(t, os.WriteFile(file, []byte("console.log(2)"), 0644))
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Second)))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	assert.NotEqual(t, tag, rec.Header().Get("ETag"))
+
+	// Identical content yields the same ETag regardless of the name
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "copy.js"), []byte("console.log(2)"), 0644))
+	copyTag, ok := etags.etag("/copy.js")
+	require.True(t, ok)
+	assert.Equal(t, rec.Header().Get("ETag"), copyTag)
+
+	// Directories have no ETag
+	_, ok = etags.etag("/")
+	assert.False(t, ok)
+}
+
+func TestCompressedResponsesWeakenETag(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.css"), []byte(strings.Repeat("a{}", 1000)), 0644))
+
+	root := http.Dir(dir)
+	etags := newContentETags(root)
+	handler := compress(etags.middleware(http.FileServer(root)), CompressOptions{Types: DefaultCompressTypes})
+
+	req := httptest.NewRequest(http.MethodGet, "/app.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	strong, _ := etags.etag("/app.css")
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "W/"+strong, rec.Header().Get("ETag"))
+
+	// The weak ETag still revalidates
+	req = httptest.NewRequest(http.MethodGet, "/app.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
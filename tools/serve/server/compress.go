@@ -0,0 +1,302 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encodings lists the supported content codings in order of server
+// preference, along with the file extension of precompressed siblings.
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"zstd", ".zst"},
+	{"gzip", ".gz"},
+}
+
+// DefaultCompressTypes are the content types compressed on the fly unless
+// overridden with --compress-types.
+var DefaultCompressTypes = []string{
+	"text/*",
+	"application/javascript",
+	"application/json",
+	"application/manifest+json",
+	"application/wasm",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// CompressOptions controls on-the-fly compression.
+type CompressOptions struct {
+	// MinSize is the smallest response, in bytes, worth compressing.
+	MinSize int64
+	// Types is the allowlist of content types. Entries ending in /* match
+	// every subtype.
+	Types []string
+}
+
+// negotiateEncoding picks the best supported coding from an Accept-Encoding
+// header. It returns the empty string if the identity coding should be used.
+func negotiateEncoding(header string, supported []string) string {
+	if header == "" {
+		return ""
+	}
+
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = parsed
+			}
+		}
+		qualities[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range supported {
+		q, ok := qualities[name]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// precompressed wraps the handler so that a request for a file is answered
+// with a precompressed sibling (app.js.br, app.js.gz) when one exists and the
+// client accepts its coding. The file itself has to be served too, so that
+// hiding secret.txt also hides secret.txt.gz.
+func precompressed(next http.Handler, root http.FileSystem) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || strings.HasSuffix(r.URL.Path, "/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		original, err := root.Open(r.URL.Path)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		original.Close()
+
+		var available []string
+		exts := map[string]string{}
+		for _, enc := range encodings {
+			f, err := root.Open(r.URL.Path + enc.ext)
+			if err != nil {
+				continue
+			}
+			info, err := f.Stat()
+			f.Close()
+			if err == nil && !info.IsDir() {
+				available = append(available, enc.name)
+				exts[enc.name] = enc.ext
+			}
+		}
+		if len(available) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		addVary(w.Header(), "Accept-Encoding")
+		chosen := negotiateEncoding(r.Header.Get("Accept-Encoding"), available)
+		if chosen == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		f, err := root.Open(r.URL.Path + exts[chosen])
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ctype := mime.TypeByExtension(path.Ext(r.URL.Path)); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		w.Header().Set("Content-Encoding", chosen)
+		http.ServeContent(w, r, r.URL.Path, info.ModTime(), f)
+	})
+}
+
+// compress wraps the handler so that responses are compressed on the fly
+// using the best coding the client accepts. HEAD requests get the headers
+// the matching GET would have.
+func compress(next http.Handler, opts CompressOptions) http.Handler {
+	supported := make([]string, len(encodings))
+	for i, enc := range encodings {
+		supported[i] = enc.name
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addVary(w.Header(), "Accept-Encoding")
+
+		chosen := negotiateEncoding(r.Header.Get("Accept-Encoding"), supported)
+		if chosen == "" || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: chosen, opts: opts, head: r.Method == http.MethodHead}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter decides whether to compress once the headers are known and
+// then streams the body through the encoder. Responses to HEAD requests have
+// no body to encode, so only their headers change.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	opts        CompressOptions
+	head        bool
+	encoder     io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	if cw.shouldCompress(status, h) {
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		h.Set("Content-Encoding", cw.encoding)
+		// The encoded bytes differ from the file, so a strong ETag no longer holds.
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+		if !cw.head {
+			cw.encoder = newEncoder(cw.encoding, cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) shouldCompress(status int, h http.Header) bool {
+	if status != http.StatusOK || h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil && n < cw.opts.MinSize {
+			return false
+		}
+	}
+	return typeAllowed(h.Get("Content-Type"), cw.opts.Types)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *compressWriter) Flush() {
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close flushes any buffered compressed output.
+func (cw *compressWriter) Close() error {
+	if cw.encoder == nil {
+		return nil
+	}
+	return cw.encoder.Close()
+}
+
+// newEncoder returns a writer which compresses into w using the given coding.
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case "br":
+		return brotli.NewWriterLevel(w, brotli.DefaultCompression)
+	case "zstd":
+		enc, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		return enc
+	default:
+		gz, _ := gzip.NewWriterLevel(w, gzip.DefaultCompression)
+		return gz
+	}
+}
+
+// addVary adds a field to the Vary header unless it is already listed.
+func addVary(h http.Header, field string) {
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
+
+// typeAllowed reports whether a Content-Type header matches the allowlist.
+func typeAllowed(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{"br", "zstd", "gzip"}
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"gzip, zstd", "zstd"},
+		{"br;q=0.5, gzip;q=0.8", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"*", "br"},
+		{"*;q=0.1, br;q=0", "zstd"},
+		{"GZIP", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.expected, negotiateEncoding(tt.header, supported))
+		})
+	}
+}
+
+func TestTypeAllowed(t *testing.T) {
+	allowed := []string{"text/*", "application/json"}
+
+	assert.True(t, typeAllowed("text/html; charset=utf-8", allowed))
+	assert.True(t, typeAllowed("text/css", allowed))
+	assert.True(t, typeAllowed("application/json", allowed))
+	assert.False(t, typeAllowed("image/png", allowed))
+	assert.False(t, typeAllowed("application/jsonx", allowed))
+	assert.False(t, typeAllowed("", allowed))
+}
+
+func TestAddVary(t *testing.T) {
+	h := http.Header{}
+	addVary(h, "Accept-Encoding")
+	addVary(h, "accept-encoding")
+	addVary(h, "Origin")
+	assert.Equal(t, []string{"Accept-Encoding", "Origin"}, h.Values("Vary"))
+}
+
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "br":
+		r = brotli.NewReader(body)
+	case "zstd":
+		dec, err := zstd.NewReader(body)
+		require.NoError(t, err)
+		defer dec.Close()
+		r = dec
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		require.NoError(t, err)
+		r = gz
+	default:
+		r = body
+	}
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestCompressOnTheFly(t *testing.T) {
+	dir := t.TempDir()
+	large := strings.Repeat("compress me please ", 200)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.txt"), []byte(large), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.txt"), []byte("tiny"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "image.png"), []byte(large), 0644))
+
+	handler := compress(http.FileServer(http.Dir(dir)), CompressOptions{
+		MinSize: 1024,
+		Types:   []string{"text/*"},
+	})
+
+	for _, encoding := range []string{"br", "zstd", "gzip"} {
+		t.Run(encoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/large.txt", nil)
+			req.Header.Set("Accept-Encoding", encoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, encoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+			assert.Empty(t, rec.Header().Get("Content-Length"))
+			assert.Less(t, rec.Body.Len(), len(large))
+			assert.Equal(t, large, decode(t, encoding, rec.Body))
+		})
+	}
+
+	t.Run("below minimum size", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/small.txt", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "tiny", rec.Body.String())
+	})
+
+	t.Run("type not allowed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image.png", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, rec.Body.String())
+	})
+
+	t.Run("client does not accept", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/large.txt", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, rec.Body.String())
+	})
+
+	t.Run("range request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/large.txt", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("Range", "bytes=0-7")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusPartialContent, rec.Code)
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, "compress", rec.Body.String())
+	})
+}
+
+func TestCompressHeadMatchesGet(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.txt": strings.Repeat("a", 3000)})
+	handler := NewHandler(Options{
+		Mounts:   []Mount{{Prefix: "/", Dir: dir}},
+		Compress: &CompressOptions{MinSize: 1024, Types: []string{"text/*"}},
+	})
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/a.txt", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	get, head := serve(http.MethodGet), serve(http.MethodHead)
+	assert.Equal(t, "gzip", head.Header().Get("Content-Encoding"))
+	assert.Equal(t, get.Header(), head.Header())
+	assert.Empty(t, head.Body.String())
+}
+
+func TestPrecompressedSiblings(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("original"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js.br"), []byte("brotli bytes"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js.gz"), []byte("gzip bytes"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plain.js"), []byte("plain"), 0644))
+
+	root := http.Dir(dir)
+	handler := precompressed(http.FileServer(root), root)
+
+	tests := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		expectedEncoding string
+		expectedBody     string
+		expectedVary     string
+	}{
+		{"prefers brotli", "/app.js", "gzip, br", "br", "brotli bytes", "Accept-Encoding"},
+		{"falls back to gzip", "/app.js", "gzip", "gzip", "gzip bytes", "Accept-Encoding"},
+		{"identity", "/app.js", "", "", "original", "Accept-Encoding"},
+		{"no siblings", "/plain.js", "gzip, br", "", "plain", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.expectedEncoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, tt.expectedBody, rec.Body.String())
+			assert.Equal(t, tt.expectedVary, rec.Header().Get("Vary"))
+			assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+		})
+	}
+}
+
+func TestPrecompressedRespectsHiddenFiles(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".gitignore":    "secret.txt\n",
+		"secret.txt":    "secret",
+		"secret.txt.gz": "gzip bytes",
+	})
+	root := newHiddenFS(http.Dir(dir))
+	handler := precompressed(http.FileServer(root), root)
+
+	req := httptest.NewRequest(http.MethodGet, "/secret.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "gzip bytes")
+}
+
+func TestPrecompressedNotRecompressed(t *testing.T) {
+	dir := t.TempDir()
+	large := strings.Repeat("a", 4096)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.css"), []byte(large), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.css.gz"), []byte("gzip bytes"), 0644))
+
+	root := http.Dir(dir)
+	handler := compress(precompressed(http.FileServer(root), root), CompressOptions{
+		MinSize: 0,
+		Types:   DefaultCompressTypes,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/app.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "gzip bytes", rec.Body.String())
+	assert.Equal(t, []string{"Accept-Encoding"}, rec.Header().Values("Vary"))
+}
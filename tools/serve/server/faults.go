@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FaultRule injects a failure into responses for paths matching a glob.
+type FaultRule struct {
+	// Path is a glob matched like cache rules.
+	Path string `json:"path"`
+	// Probability is the chance, from 0 to 1, that the rule fires.
+	Probability float64 `json:"probability"`
+	// Status replaces the response with this status code.
+	Status int `json:"status,omitempty"`
+	// Delay waits before responding.
+	Delay time.Duration `json:"-"`
+	// Truncate cuts the connection after this many bytes of the body.
+	Truncate int64 `json:"truncate,omitempty"`
+	// Drop closes the connection without a response.
+	Drop bool `json:"drop,omitempty"`
+}
+
+// UnmarshalJSON accepts the delay as a duration string such as "2s".
+func (f *FaultRule) UnmarshalJSON(b []byte) error {
+	type plain FaultRule
+	aux := struct {
+		*plain
+		Delay       string   `json:"delay"`
+		Probability *float64 `json:"probability"`
+	}{plain: (*plain)(f)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	f.Probability = 1
+	if aux.Probability != nil {
+		f.Probability = *aux.Probability
+	}
+	if aux.Delay != "" {
+		d, err := time.ParseDuration(aux.Delay)
+		if err != nil {
+			return fmt.Errorf("invalid delay %q: %w", aux.Delay, err)
+		}
+		f.Delay = d
+	}
+	return nil
+}
+
+// validate reports configuration mistakes in the rule.
+func (f FaultRule) validate() error {
+	if f.Path == "" {
+		return errors.New("fault rule is missing a path")
+	}
+	if f.Probability < 0 || f.Probability > 1 {
+		return fmt.Errorf("fault probability for %s must be between 0 and 1", f.Path)
+	}
+	if f.Status != 0 && (f.Status < 100 || f.Status > 999) {
+		return fmt.Errorf("invalid fault status %d for %s", f.Status, f.Path)
+	}
+	if f.Status == 0 && f.Delay == 0 && f.Truncate == 0 && !f.Drop {
+		return fmt.Errorf("fault rule for %s does nothing", f.Path)
+	}
+	return nil
+}
+
+// ParseFaultRule parses a glob=action,... flag. Actions are status:CODE,
+// delay:DURATION, truncate:BYTES, drop and p:PROBABILITY, for example
+// "/api/*=status:503,p:0.3".
+func ParseFaultRule(s string) (FaultRule, error) {
+	pattern, actions, ok := strings.Cut(s, "=")
+	rule := FaultRule{Path: strings.TrimSpace(pattern), Probability: 1}
+	if !ok {
+		return FaultRule{}, fmt.Errorf("fault must be in the form glob=action,..., got %q", s)
+	}
+
+	for _, action := range strings.Split(actions, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(action), ":")
+		var err error
+		switch key {
+		case "status":
+			rule.Status, err = strconv.Atoi(value)
+		case "delay":
+			rule.Delay, err = time.ParseDuration(value)
+		case "truncate":
+			rule.Truncate, err = strconv.ParseInt(value, 10, 64)
+		case "drop":
+			rule.Drop = true
+		case "p":
+			rule.Probability, err = strconv.ParseFloat(value, 64)
+		default:
+			err = errors.New("unknown action")
+		}
+		if err != nil {
+			return FaultRule{}, fmt.Errorf("invalid fault action %q: %w", action, err)
+		}
+	}
+
+	if err := rule.validate(); err != nil {
+		return FaultRule{}, err
+	}
+	return rule, nil
+}
+
+// LoadFaultRules reads a JSON array of fault rules.
+func LoadFaultRules(filename string) ([]FaultRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []FaultRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("error in %s: %w", filename, err)
+		}
+	}
+	return rules, nil
+}
+
+// errTruncated stops the wrapped handler once a truncated body is sent.
+var errTruncated = errors.New("response truncated by fault injection")
+
+// injectFaults wraps the handler so that matching requests fail. Rules are
+// checked in order and the first one that fires is applied. random returns
+// numbers in [0, 1) and is swapped out in tests.
+func injectFaults(next http.Handler, rules []FaultRule, random func() float64) http.Handler {
+	if random == nil {
+		random = rand.Float64
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var fault *FaultRule
+		for i := range rules {
+			if globMatch(rules[i].Path, r.URL.Path) && random() < rules[i].Probability {
+				fault = &rules[i]
+				break
+			}
+		}
+		if fault == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := sleep(r.Context(), fault.Delay); err != nil {
+			return
+		}
+
+		if fault.Drop {
+			panic(http.ErrAbortHandler)
+		}
+
+		if fault.Status != 0 {
+			w.Header().Set("X-Serve-Fault", "status")
+			http.Error(w, fmt.Sprintf("Injected fault: %d %s", fault.Status, http.StatusText(fault.Status)), fault.Status)
+			return
+		}
+
+		if fault.Truncate > 0 {
+			tw := &truncatingWriter{ResponseWriter: w, remaining: fault.Truncate}
+			next.ServeHTTP(tw, r)
+			if tw.truncated {
+				http.NewResponseController(w).Flush()
+				panic(http.ErrAbortHandler)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// truncatingWriter stops writing the body after a fixed number of bytes.
+type truncatingWriter struct {
+	http.ResponseWriter
+	remaining int64
+	truncated bool
+}
+
+func (tw *truncatingWriter) Write(b []byte) (int, error) {
+	if int64(len(b)) <= tw.remaining {
+		tw.remaining -= int64(len(b))
+		return tw.ResponseWriter.Write(b)
+	}
+
+	n, err := tw.ResponseWriter.Write(b[:tw.remaining])
+	tw.remaining = 0
+	tw.truncated = true
+	if err != nil {
+		return n, err
+	}
+	return n, errTruncated
+}
+
+func (tw *truncatingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
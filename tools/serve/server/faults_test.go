@@ -0,0 +1,139 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFaultRule(t *testing.T) {
+	rule, err := ParseFaultRule("/api/*=status:503,p:0.3,delay:100ms")
+	require.NoError(t, err)
+	assert.Equal(t, FaultRule{
+		Path:        "/api/*",
+		Probability: 0.3,
+		Status:      503,
+		Delay:       100 * time.Millisecond,
+	}, rule)
+
+	rule, err = ParseFaultRule("*.bin=truncate:1024")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), rule.Truncate)
+	assert.Equal(t, 1.0, rule.Probability)
+
+	rule, err = ParseFaultRule("/ws=drop")
+	require.NoError(t, err)
+	assert.True(t, rule.Drop)
+
+	for _, invalid := range []string{
+		"/api/*",
+		"=status:500",
+		"/api/*=status:abc",
+		"/api/*=status:42",
+		"/api/*=p:2,status:500",
+		"/api/*=explode",
+		"/api/*=p:0.5",
+	} {
+		_, err := ParseFaultRule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLoadFaultRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "faults.json")
+	require.NoError(t, os.WriteFile(file, []byte(`[
+		{"path": "/api/*", "status": 500, "probability": 0.25},
+		{"path": "*.js", "delay": "2s"},
+		{"path": "/never", "status": 500, "probability": 0}
+	]`), 0644))
+
+	rules, err := LoadFaultRules(file)
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	assert.Equal(t, FaultRule{Path: "/api/*", Probability: 0.25, Status: 500}, rules[0])
+	assert.Equal(t, FaultRule{Path: "*.js", Probability: 1, Delay: 2 * time.Second}, rules[1])
+	assert.Equal(t, 0.0, rules[2].Probability)
+
+	require.NoError(t, os.WriteFile(file, []byte(`[{"path": "*.js", "delay": "soon"}]`), 0644))
+	_, err = LoadFaultRules(file)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(file, []byte(`[{"path": "*.js"}]`), 0644))
+	_, err = LoadFaultRules(file)
+	assert.Error(t, err)
+
+	_, err = LoadFaultRules(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestInjectFaultsStatus(t *testing.T) {
+	roll := 0.0
+	random := func() float64 { return roll }
+	handler := injectFaults(okHandler, []FaultRule{
+		{Path: "/api/*", Probability: 0.5, Status: http.StatusServiceUnavailable},
+	}, random)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "status", rec.Header().Get("X-Serve-Fault"))
+
+	// The roll misses
+	roll = 0.9
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Other paths are untouched
+	roll = 0
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestInjectFaultsDelay(t *testing.T) {
+	handler := injectFaults(okHandler, []FaultRule{
+		{Path: "*", Probability: 1, Delay: 100 * time.Millisecond},
+	}, nil)
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, "ok", rec.Body.String())
+}
+
+func TestInjectFaultsTruncate(t *testing.T) {
+	dir := writeTree(t, map[string]string{"big.txt": strings.Repeat("x", 10000)})
+	server := httptest.NewServer(injectFaults(http.FileServer(http.Dir(dir)), []FaultRule{
+		{Path: "/big.txt", Probability: 1, Truncate: 100},
+	}, nil))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/big.txt")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.Error(t, err)
+	assert.Len(t, body, 100)
+}
+
+func TestInjectFaultsDrop(t *testing.T) {
+	server := httptest.NewServer(injectFaults(okHandler, []FaultRule{
+		{Path: "*", Probability: 1, Drop: true},
+	}, nil))
+	defer server.Close()
+
+	_, err := http.Get(server.URL + "/page.html")
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+// Package server implements the HTTP handler behind serve: a static file
+// server along with the middleware for auth, headers, caching, compression
+// and simulating slow or failing networks.
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Options describes the handler returned by NewHandler. The zero value
+// serves the current directory with every optional feature turned off. The
+// Parse functions in this package build the values of most fields.
+type Options struct {
+	// Mounts are the directories to serve. Empty serves the current
+	// directory at the root.
+	Mounts []Mount
+	// All serves dotfiles and paths matched by .gitignore and .serveignore.
+	All bool
+	// ContentETags sends ETags hashed from file contents instead of built
+	// from the modification time and size.
+	ContentETags bool
+	// Precompressed serves siblings such as app.js.br when clients accept them.
+	Precompressed bool
+	// Preload adds Link preload headers to HTML pages.
+	Preload bool
+	// Markdown renders Markdown files as HTML for browsers.
+	Markdown bool
+	// MarkdownIndex renders the README.md or index.md of a directory in
+	// place of its listing. It needs Markdown.
+	MarkdownIndex bool
+	// Archives allows downloading directories with ?download=zip.
+	Archives bool
+
+	// Proxies forward requests under their prefixes to other servers.
+	Proxies []ProxyRoute
+	// ProxyCredentials forwards the basic auth and access token which serve
+	// checks to the proxied servers instead of removing them.
+	ProxyCredentials bool
+	// MockDir answers requests matching fixtures in the directory.
+	MockDir string
+	// Compress compresses responses on the fly. Nil disables it.
+	Compress *CompressOptions
+	// Throttle limits bandwidth.
+	Throttle ThrottleOptions
+	// WriteTimeout is the write timeout of the server. Throttled responses
+	// and archives, which can take far longer as a whole, get this long for
+	// every write instead.
+	WriteTimeout time.Duration
+	// Latency delays matching requests.
+	Latency []LatencyRule
+	// Faults make matching requests fail.
+	Faults []FaultRule
+	// Cache sets the Cache-Control header.
+	Cache CachePolicy
+
+	// Admin serves the endpoints under /_serve/. Nil disables them.
+	Admin *AdminOptions
+	// WebDAV lets WebDAV clients browse the mounts. Nil disables it.
+	WebDAV *WebDAVOptions
+	// Token requires clients to present the access token. Nil disables it.
+	Token *AccessToken
+	// User and Password require HTTP basic auth when User is set.
+	User, Password string
+	// CORS allows cross-origin requests. Nil disables it.
+	CORS *CORSOptions
+	// Headers are added to every response, replacing any set by the handler.
+	Headers http.Header
+	// AccessLog logs every request. Nil disables it.
+	AccessLog *log.Logger
+}
+
+// Validate reports mistakes which NewHandler cannot recover from.
+func (o Options) Validate() error {
+	seen := map[string]bool{}
+	for _, m := range o.Mounts {
+		if seen[m.Prefix] {
+			return fmt.Errorf("%s is mounted more than once", m.Prefix)
+		}
+		seen[m.Prefix] = true
+	}
+
+	if o.MockDir != "" {
+		info, err := os.Stat(o.MockDir)
+		if err != nil {
+			return fmt.Errorf("error reading fixtures: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("error reading fixtures %s: not a directory", o.MockDir)
+		}
+	}
+	return nil
+}
+
+// NewHandler composes the file server and middleware described by the
+// options. Options should be checked with Validate first.
+func NewHandler(opts Options) http.Handler {
+	mounts := opts.Mounts
+	if len(mounts) == 0 {
+		mounts = []Mount{{Prefix: "/", Dir: "."}}
+	}
+	var handler http.Handler = newMountRouter(mounts, func(dir string) http.Handler {
+		return newFileHandler(opts, dir)
+	})
+	// WebDAV only sees the requests which no proxy or fixture answers, so
+	// that methods such as DELETE still reach them.
+	if opts.WebDAV != nil {
+		handler = withWebDAV(handler, mounts, *opts.WebDAV, opts.All)
+	}
+
+	if len(opts.Proxies) > 0 {
+		var credentials serveCredentials
+		if !opts.ProxyCredentials {
+			credentials = serveCredentials{BasicAuth: opts.User != "", Token: opts.Token != nil}
+		}
+		handler = withProxies(handler, opts.Proxies, credentials)
+	}
+	if opts.MockDir != "" {
+		handler = mockAPI(handler, opts.MockDir)
+	}
+	if opts.Compress != nil {
+		handler = compress(handler, *opts.Compress)
+	}
+	if opts.Throttle.Global != nil || opts.Throttle.PerConn > 0 {
+		handler = throttle(handler, opts.Throttle, opts.WriteTimeout)
+	}
+	if len(opts.Latency) > 0 {
+		handler = withLatency(handler, opts.Latency)
+	}
+	if len(opts.Faults) > 0 {
+		handler = injectFaults(handler, opts.Faults, nil)
+	}
+	handler = withCachePolicy(handler, opts.Cache)
+
+	var stats *Metrics
+	if opts.Admin != nil {
+		admin := *opts.Admin
+		if admin.Metrics == nil {
+			admin.Metrics = NewMetrics()
+		}
+		stats = admin.Metrics
+		handler = adminRoutes(handler, admin)
+	}
+
+	if opts.Token != nil {
+		handler = opts.Token.middleware(handler)
+	}
+	if opts.User != "" {
+		handler = basicAuth(handler, opts.User, opts.Password)
+	}
+
+	// CORS wraps auth so that preflight requests, which never carry
+	// credentials, can be answered.
+	if opts.CORS != nil {
+		handler = withCORS(handler, *opts.CORS)
+	}
+	if len(opts.Headers) > 0 {
+		handler = withHeaders(handler, opts.Headers)
+	}
+
+	if stats != nil {
+		handler = stats.middleware(handler)
+	}
+	if opts.AccessLog != nil {
+		handler = accessLog(handler, opts.AccessLog)
+	}
+	return handler
+}
+
+// newFileHandler returns a handler which serves the directory, along with
+// the features that need direct access to its files.
+func newFileHandler(opts Options, dir string) http.Handler {
+	var root http.FileSystem = http.Dir(dir)
+	if !opts.All {
+		root = newHiddenFS(root)
+	}
+	var handler http.Handler = http.FileServer(root)
+
+	if opts.ContentETags {
+		handler = newContentETags(root).middleware(handler)
+	} else {
+		handler = modTimeETags(handler, root)
+	}
+	if opts.Preload {
+		handler = newPreloadLinks(root).middleware(handler)
+	}
+	if opts.Precompressed {
+		handler = precompressed(handler, root)
+	}
+	if opts.Markdown {
+		handler = renderMarkdown(handler, root, opts.MarkdownIndex)
+	}
+	if opts.Archives {
+		name := filepath.Base(dir)
+		if abs, err := filepath.Abs(dir); err == nil {
+			name = filepath.Base(abs)
+		}
+		handler = archiveDownloads(handler, root, name, opts.Markdown && opts.MarkdownIndex, opts.WriteTimeout)
+	}
+	return handler
+}
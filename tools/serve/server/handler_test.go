@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHandler(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"index.txt": "hello",
+		".env":      "SECRET=1",
+	})
+	var logs bytes.Buffer
+	handler := NewHandler(Options{
+		Mounts:    []Mount{{Prefix: "/", Dir: dir}},
+		Cache:     CachePolicy{Mode: CacheNoStore},
+		Headers:   http.Header{"X-Served-By": {"serve"}},
+		AccessLog: log.New(&logs, "", 0),
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index.txt", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello", rec.Body.String())
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "serve", rec.Header().Get("X-Served-By"))
+	assert.Contains(t, logs.String(), `"GET /index.txt HTTP/1.1" 200 5`)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.env", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestNewHandlerHidesConfigFiles(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"serve.yaml":     "profiles:\n  private:\n    auth: alice:s3cret\n",
+		"serve.yml":      "port: 9000\n",
+		"docs/serve.yml": "not a config file",
+	})
+
+	get := func(handler http.Handler, path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	handler := NewHandler(Options{Mounts: []Mount{{Prefix: "/", Dir: dir}}})
+	assert.Equal(t, http.StatusNotFound, get(handler, "/serve.yaml"))
+	assert.Equal(t, http.StatusNotFound, get(handler, "/serve.yml"))
+	assert.Equal(t, http.StatusOK, get(handler, "/docs/serve.yml"))
+
+	handler = NewHandler(Options{Mounts: []Mount{{Prefix: "/", Dir: dir}}, All: true})
+	assert.Equal(t, http.StatusOK, get(handler, "/serve.yaml"))
+}
+
+func TestNewHandlerCORSWrapsAuth(t *testing.T) {
+	handler := NewHandler(Options{
+		Mounts:   []Mount{{Prefix: "/", Dir: t.TempDir()}},
+		User:     "user",
+		Password: "pass",
+		CORS:     &CORSOptions{Origins: []string{"https://app.example"}},
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, Options{}.Validate())
+	assert.NoError(t, Options{MockDir: dir}.Validate())
+
+	assert.Error(t, Options{Mounts: []Mount{
+		{Prefix: "/docs", Dir: "a"},
+		{Prefix: "/docs", Dir: "b"},
+	}}.Validate())
+	assert.Error(t, Options{MockDir: filepath.Join(dir, "missing")}.Validate())
+
+	file := writeTree(t, map[string]string{"fixtures": ""})
+	assert.Error(t, Options{MockDir: filepath.Join(file, "fixtures")}.Validate())
+}
+
+func TestNewHandlerWebDAVLeavesProxiesAndMocks(t *testing.T) {
+	var backendMethods []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendMethods = append(backendMethods, r.Method)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer backend.Close()
+	route, err := ParseProxy("/api=" + backend.URL)
+	require.NoError(t, err)
+
+	site := writeTree(t, map[string]string{
+		"api/users/1":  "on disk",
+		"mock/items/1": "on disk",
+		"notes.txt":    "hello",
+	})
+	fixtures := writeTree(t, map[string]string{"mock/items/[id].PUT.json": `{"updated":true}`})
+	handler := NewHandler(Options{
+		Mounts:  []Mount{{Prefix: "/", Dir: site}},
+		Proxies: []ProxyRoute{route},
+		MockDir: fixtures,
+		WebDAV:  &WebDAVOptions{Write: true},
+	})
+
+	rec := davRequest(t, handler, http.MethodDelete, "/api/users/1", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = davRequest(t, handler, http.MethodOptions, "/api/users", "")
+	assert.Empty(t, rec.Header().Get("DAV"))
+	assert.Equal(t, []string{http.MethodDelete, http.MethodOptions}, backendMethods)
+
+	rec = davRequest(t, handler, http.MethodPut, "/mock/items/1", "changed")
+	assert.Equal(t, `{"updated":true}`, rec.Body.String())
+
+	for _, name := range []string{"api/users/1", "mock/items/1"} {
+		content, err := os.ReadFile(filepath.Join(site, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		assert.Equal(t, "on disk", string(content), name)
+	}
+
+	// Everything else is still served over WebDAV.
+	rec = davRequest(t, handler, http.MethodPut, "/notes.txt", "changed")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	content, err := os.ReadFile(filepath.Join(site, "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(content))
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IsolationPresets are the header sets accepted by --isolation. Both enable
+// cross-origin isolation, which SharedArrayBuffer and WASM threads require.
+var IsolationPresets = map[string]http.Header{
+	"require-corp": {
+		"Cross-Origin-Opener-Policy":   {"same-origin"},
+		"Cross-Origin-Embedder-Policy": {"require-corp"},
+	},
+	"credentialless": {
+		"Cross-Origin-Opener-Policy":   {"same-origin"},
+		"Cross-Origin-Embedder-Policy": {"credentialless"},
+	},
+}
+
+// ParseHeader parses a "Name: value" flag.
+func ParseHeader(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("header must be in the form 'Name: value', got %q", s)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// withHeaders wraps the handler so that every response carries the headers.
+// They are set just before the response is written so they take precedence
+// over anything set by the handler, or after the handler if it wrote nothing.
+func withHeaders(next http.Handler, headers http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hw := &hookWriter{ResponseWriter: w, before: func(int) {
+			for name, values := range headers {
+				w.Header()[name] = slices.Clone(values)
+			}
+		}}
+		next.ServeHTTP(hw, r)
+		hw.finish()
+	})
+}
+
+// CORSOptions controls cross-origin resource sharing.
+type CORSOptions struct {
+	// Origins lists the allowed origins. "*" allows any origin.
+	Origins []string
+	// Credentials allows cookies and HTTP auth on cross-origin requests.
+	Credentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// allowed reports whether the origin may access the server.
+func (o CORSOptions) allowed(origin string) bool {
+	for _, allowed := range o.Origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// withCORS wraps the handler so that allowed origins receive CORS headers and
+// preflight requests are answered directly.
+func withCORS(next http.Handler, opts CORSOptions) http.Handler {
+	wildcard := slices.Contains(opts.Origins, "*") && !opts.Credentials
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if !wildcard {
+			addVary(w.Header(), "Origin")
+		}
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin == "" || !opts.allowed(origin) {
+			if preflight {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		if wildcard {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if opts.Credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if !opts.Credentials {
+				h.Set("Access-Control-Expose-Headers", "*")
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.Set("Access-Control-Allow-Methods", r.Header.Get("Access-Control-Request-Method"))
+		if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", reqHeaders)
+		}
+		if opts.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
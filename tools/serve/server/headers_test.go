@@ -0,0 +1,136 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeader(t *testing.T) {
+	name, value, err := ParseHeader("x-frame-options: DENY")
+	require.NoError(t, err)
+	assert.Equal(t, "X-Frame-Options", name)
+	assert.Equal(t, "DENY", value)
+
+	name, value, err = ParseHeader("Link: <a.css>; rel=preload")
+	require.NoError(t, err)
+	assert.Equal(t, "Link", name)
+	assert.Equal(t, "<a.css>; rel=preload", value)
+
+	for _, invalid := range []string{"NoColon", ": value", "Bad Name: x"} {
+		_, _, err := ParseHeader(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestWithHeadersOverridesHandler(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte("ok"))
+	})
+	handler := withHeaders(inner, http.Header{
+		"Cache-Control": {"max-age=60"},
+		"X-Test":        {"a", "b"},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "max-age=60", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, []string{"a", "b"}, rec.Header().Values("X-Test"))
+}
+
+func TestWithHeadersWithoutBody(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+	})
+	handler := withHeaders(inner, http.Header{"X-A": {"1"}})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/?download=zip", nil))
+	assert.Equal(t, "1", rec.Header().Get("X-A"))
+	assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+}
+
+func TestIsolationPresets(t *testing.T) {
+	for name, preset := range IsolationPresets {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, "same-origin", preset.Get("Cross-Origin-Opener-Policy"))
+			assert.Equal(t, name, preset.Get("Cross-Origin-Embedder-Policy"))
+		})
+	}
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	handler := withCORS(okHandler, CORSOptions{Origins: []string{"https://app.example"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/data.json", nil)
+	req.Header.Set("Origin", "https://app.example")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://app.example", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	// Other origins get the content without CORS headers
+	req = httptest.NewRequest(http.MethodGet, "/data.json", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSWildcard(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://any.example")
+
+	rec := httptest.NewRecorder()
+	withCORS(okHandler, CORSOptions{Origins: []string{"*"}}).ServeHTTP(rec, req)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Vary"))
+
+	// Credentials cannot be combined with a literal wildcard, so the origin is echoed
+	rec = httptest.NewRecorder()
+	withCORS(okHandler, CORSOptions{Origins: []string{"*"}, Credentials: true}).ServeHTTP(rec, req)
+	assert.Equal(t, "https://any.example", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+}
+
+func TestCORSPreflight(t *testing.T) {
+	called := false
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+	handler := withCORS(basicAuth(inner, "alice", "secret"), CORSOptions{
+		Origins: []string{"https://app.example"},
+		MaxAge:  10 * time.Minute,
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/api", nil)
+	req.Header.Set("Origin", "https://app.example")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	req.Header.Set("Access-Control-Request-Headers", "content-type, authorization")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.False(t, called)
+	assert.Equal(t, "https://app.example", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "PUT", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "content-type, authorization", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+
+	// Preflight from a disallowed origin
+	req.Header.Set("Origin", "https://evil.example")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ignoreFiles are read from every directory, in order, to hide paths using
+// gitignore syntax. Later files take precedence.
+var ignoreFiles = []string{".gitignore", ".serveignore"}
+
+// ConfigFileNames are the config files which serve reads from the directory
+// it serves. They can hold credentials, so they are hidden at the root of
+// every served directory.
+var ConfigFileNames = []string{"serve.yaml", "serve.yml", ".serve.json"}
+
+// ignorePattern is a single compiled line of an ignore file.
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// parseIgnore compiles the patterns in an ignore file. Paths are matched
+// relative to the directory containing the file.
+func parseIgnore(r io.Reader) []ignorePattern {
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if p, ok := compileIgnorePattern(scanner.Text()); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// compileIgnorePattern translates one gitignore line into a regular expression.
+func compileIgnorePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, "\r")
+	if strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line[:len(line)-2], " ") + " "
+	} else {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to the ignore file's
+	// directory. Otherwise it matches at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**") && i+2 == len(line):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(line):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(line[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// ignoreMatcher decides whether paths are hidden by the ignore files found
+// in the served tree. Parsed files are cached until they change.
+type ignoreMatcher struct {
+	fs    http.FileSystem
+	mu    sync.Mutex
+	cache map[string]cachedIgnore
+}
+
+type cachedIgnore struct {
+	modTime  time.Time
+	size     int64
+	patterns []ignorePattern
+}
+
+func newIgnoreMatcher(fsys http.FileSystem) *ignoreMatcher {
+	return &ignoreMatcher{fs: fsys, cache: map[string]cachedIgnore{}}
+}
+
+// patterns returns the compiled patterns of every ignore file in dir.
+func (m *ignoreMatcher) patterns(dir string) []ignorePattern {
+	var all []ignorePattern
+	for _, name := range ignoreFiles {
+		file := path.Join(dir, name)
+		f, err := m.fs.Open(file)
+		if err != nil {
+			continue
+		}
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			f.Close()
+			continue
+		}
+
+		m.mu.Lock()
+		cached, ok := m.cache[file]
+		m.mu.Unlock()
+		if !ok || !cached.modTime.Equal(info.ModTime()) || cached.size != info.Size() {
+			cached = cachedIgnore{modTime: info.ModTime(), size: info.Size(), patterns: parseIgnore(f)}
+			m.mu.Lock()
+			m.cache[file] = cached
+			m.mu.Unlock()
+		}
+		f.Close()
+		all = append(all, cached.patterns...)
+	}
+	return all
+}
+
+// ignored reports whether the slash-separated path, relative to the root, is
+// hidden. A path inside an ignored directory is always ignored.
+func (m *ignoreMatcher) ignored(name string, isDir bool) bool {
+	parts := strings.Split(strings.Trim(path.Clean("/"+name), "/"), "/")
+	if parts[0] == "" {
+		return false
+	}
+
+	for i := range parts {
+		prefixIsDir := isDir || i < len(parts)-1
+		if m.matches(parts[:i+1], prefixIsDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches applies the ignore files of every ancestor directory to the path
+// given by parts. The last matching pattern wins.
+func (m *ignoreMatcher) matches(parts []string, isDir bool) bool {
+	ignored := false
+	for depth := 0; depth < len(parts); depth++ {
+		dir := "/" + strings.Join(parts[:depth], "/")
+		rel := strings.Join(parts[depth:], "/")
+		for _, p := range m.patterns(dir) {
+			if p.dirOnly && !isDir {
+				continue
+			}
+			if p.re.MatchString(rel) {
+				ignored = !p.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// hiddenFS is a file system which hides dot-paths and paths matched by ignore
+// files, both from directory listings and from direct requests.
+type hiddenFS struct {
+	http.FileSystem
+	hideDot bool
+	ignore  *ignoreMatcher
+}
+
+// newHiddenFS wraps the file system so that hidden paths do not exist.
+func newHiddenFS(fsys http.FileSystem) *hiddenFS {
+	return &hiddenFS{FileSystem: fsys, hideDot: true, ignore: newIgnoreMatcher(fsys)}
+}
+
+// hidden reports whether the slash-separated path should not be served.
+func (h *hiddenFS) hidden(name string, isDir bool) bool {
+	if slices.Contains(ConfigFileNames, strings.TrimPrefix(name, "/")) {
+		return true
+	}
+	if h.hideDot {
+		for _, part := range strings.Split(name, "/") {
+			if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+				return true
+			}
+		}
+	}
+	return h.ignore != nil && h.ignore.ignored(name, isDir)
+}
+
+func (h *hiddenFS) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+	f, err := h.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if h.hidden(name, info.IsDir()) {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return &hiddenFile{File: f, fs: h, name: name}, nil
+}
+
+// hiddenFile filters hidden entries out of directory listings.
+type hiddenFile struct {
+	http.File
+	fs   *hiddenFS
+	name string
+}
+
+func (f *hiddenFile) Readdir(count int) ([]fs.FileInfo, error) {
+	var visible []fs.FileInfo
+	for {
+		infos, err := f.File.Readdir(count)
+		for _, info := range infos {
+			if !f.fs.hidden(path.Join(f.name, info.Name()), info.IsDir()) {
+				visible = append(visible, info)
+			}
+		}
+		if count <= 0 || len(visible) > 0 || err != nil {
+			return visible, err
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileIgnorePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		matches bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"*.log", "debug.log.txt", false, false},
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+		{"doc/*.txt", "doc/notes.txt", false, true},
+		{"doc/*.txt", "doc/server/arch.txt", false, false},
+		{"**/foo", "foo", false, true},
+		{"**/foo", "a/b/foo", false, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"abc/**", "abc/x/y", false, true},
+		{"abc/**", "abc", true, false},
+		{"file?.txt", "file1.txt", false, true},
+		{"file?.txt", "file10.txt", false, false},
+		{"[ab].txt", "a.txt", false, true},
+		{"[!ab].txt", "a.txt", false, false},
+		{"[!ab].txt", "c.txt", false, true},
+		{`\#notes`, "#notes", false, true},
+		{"secret.env ", "secret.env", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			p, ok := compileIgnorePattern(tt.pattern)
+			require.True(t, ok)
+			matched := p.re.MatchString(tt.path) && (!p.dirOnly || tt.isDir)
+			assert.Equal(t, tt.matches, matched)
+		})
+	}
+}
+
+func TestCompileIgnorePatternSkipsCommentsAndBlanks(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+		_, ok := compileIgnorePattern(line)
+		assert.False(t, ok, line)
+	}
+
+	p, ok := compileIgnorePattern("!keep.log")
+	require.True(t, ok)
+	assert.True(t, p.negate)
+}
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".gitignore":          "*.log\n!keep.log\nnode_modules/\n",
+		".serveignore":        "drafts/\n",
+		"app/.gitignore":      "/local.json\n",
+		"app/local.json":      "{}",
+		"app/config.json":     "{}",
+		"debug.log":           "",
+		"keep.log":            "",
+		"drafts/post.md":      "",
+		"node_modules/x/a.js": "",
+	})
+	m := newIgnoreMatcher(http.Dir(dir))
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"/debug.log", false, true},
+		{"/keep.log", false, false},
+		{"/drafts", true, true},
+		{"/drafts/post.md", false, true},
+		{"/node_modules/x/a.js", false, true},
+		{"/app/local.json", false, true},
+		{"/app/config.json", false, false},
+		{"/local.json", false, false},
+		{"/", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.ignored, m.ignored(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestHiddenFSBlocksDirectFetches(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".env":          "SECRET=1",
+		".git/config":   "[core]",
+		".gitignore":    "*.log\n",
+		"debug.log":     "log",
+		"index.txt":     "visible",
+		"sub/.hidden":   "hidden",
+		"sub/shown.txt": "shown",
+	})
+	server := httptest.NewServer(http.FileServer(newHiddenFS(http.Dir(dir))))
+	defer server.Close()
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/.env", http.StatusNotFound},
+		{"/.git/config", http.StatusNotFound},
+		{"/.gitignore", http.StatusNotFound},
+		{"/debug.log", http.StatusNotFound},
+		{"/sub/.hidden", http.StatusNotFound},
+		{"/index.txt", http.StatusOK},
+		{"/sub/shown.txt", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.path)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.expected, resp.StatusCode)
+		})
+	}
+}
+
+func TestHiddenFSFiltersListings(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".env":       "SECRET=1",
+		".gitignore": "*.log\n",
+		"debug.log":  "log",
+		"index.txt":  "visible",
+		"sub/a.txt":  "a",
+	})
+	server := httptest.NewServer(http.FileServer(newHiddenFS(http.Dir(dir))))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	listing := string(body)
+	assert.Contains(t, listing, "index.txt")
+	assert.Contains(t, listing, "sub/")
+	assert.NotContains(t, listing, ".env")
+	assert.NotContains(t, listing, ".gitignore")
+	assert.NotContains(t, listing, "debug.log")
+}
+
+func TestHiddenFSReaddirCount(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".a": "", ".b": "", ".c": "", "d.txt": "", "e.txt": "",
+	})
+	f, err := newHiddenFS(http.Dir(dir)).Open("/")
+	require.NoError(t, err)
+	defer f.Close()
+
+	var names []string
+	for {
+		infos, err := f.Readdir(1)
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		if err != nil {
+			break
+		}
+	}
+	assert.ElementsMatch(t, []string{"d.txt", "e.txt"}, names)
+	for _, name := range names {
+		assert.False(t, strings.HasPrefix(name, "."))
+	}
+}
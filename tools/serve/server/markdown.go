@@ -0,0 +1,258 @@
+package server
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// markdownExts are the file extensions rendered as Markdown.
+var markdownExts = []string{".md", ".markdown"}
+
+// markdownIndexes are rendered for directories without an index.html when
+// enabled, which makes relative links to directories behave like a wiki.
+var markdownIndexes = []string{"README.md", "index.md"}
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		extension.Footnote,
+		highlighting.NewHighlighting(highlighting.WithStyle("github")),
+	),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+// tocEntry is a heading listed in the table of contents.
+type tocEntry struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// renderedMarkdown is the data passed to the page template.
+type renderedMarkdown struct {
+	Title  string
+	Source string
+	TOC    []tocEntry
+	Body   template.HTML
+}
+
+// renderMarkdownPage converts Markdown source to a standalone HTML page.
+func renderMarkdownPage(source []byte, name string) ([]byte, error) {
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	page := renderedMarkdown{
+		Title:  strings.TrimSuffix(name, path.Ext(name)),
+		Source: url.PathEscape(name) + "?raw",
+	}
+	titled := false
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		id, _ := heading.AttributeString("id")
+		idStr, _ := id.([]byte)
+		entry := tocEntry{Level: heading.Level, ID: string(idStr), Text: nodeText(heading, source)}
+		if heading.Level == 1 && !titled {
+			page.Title = entry.Text
+			titled = true
+			return ast.WalkSkipChildren, nil
+		}
+		page.TOC = append(page.TOC, entry)
+		return ast.WalkSkipChildren, nil
+	})
+
+	var body bytes.Buffer
+	if err := markdown.Renderer().Render(&body, source, doc); err != nil {
+		return nil, err
+	}
+	page.Body = template.HTML(body.String())
+
+	var out bytes.Buffer
+	if err := markdownTemplate.Execute(&out, page); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// nodeText returns the plain text inside an inline node.
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch t := c.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(t.Value)
+		default:
+			b.WriteString(nodeText(c, source))
+		}
+	}
+	return b.String()
+}
+
+// isMarkdown reports whether the path names a Markdown file.
+func isMarkdown(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, m := range markdownExts {
+		if ext == m {
+			return true
+		}
+	}
+	return false
+}
+
+// wantsHTML reports whether the request comes from a browser.
+func wantsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// renderMarkdown wraps the handler so that browsers requesting a Markdown
+// file receive it rendered as HTML. The ?raw query returns the source. With
+// index set, directories render their Markdown index instead of a listing.
+func renderMarkdown(next http.Handler, root http.FileSystem, index bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.URL.Query().Has("raw") {
+			if isMarkdown(r.URL.Path) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if isMarkdown(r.URL.Path) {
+			addVary(w.Header(), "Accept")
+		}
+		if !wantsHTML(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := r.URL.Path
+		if strings.HasSuffix(name, "/") {
+			if !index {
+				next.ServeHTTP(w, r)
+				return
+			}
+			name = markdownIndex(root, name)
+		}
+		if name == "" || !isMarkdown(name) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		f, err := root.Open(name)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		source, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, "Error reading file", http.StatusInternalServerError)
+			return
+		}
+		page, err := renderMarkdownPage(source, path.Base(name))
+		if err != nil {
+			http.Error(w, "Error rendering Markdown", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeContent(w, r, "", info.ModTime(), bytes.NewReader(page))
+	})
+}
+
+// markdownIndex returns the Markdown file to render for a directory, or the
+// empty string if the directory has an index.html or no Markdown index.
+func markdownIndex(root http.FileSystem, dir string) string {
+	if f, err := root.Open(path.Join(dir, "index.html")); err == nil {
+		f.Close()
+		return ""
+	}
+	for _, name := range markdownIndexes {
+		if f, err := root.Open(path.Join(dir, name)); err == nil {
+			f.Close()
+			return path.Join(dir, name)
+		}
+	}
+	return ""
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+:root { color-scheme: light dark; }
+body { margin: 0; font: 16px/1.6 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; }
+.page { display: flex; gap: 2rem; max-width: 68rem; margin: 0 auto; padding: 2rem 1rem; }
+nav { flex: 0 0 14rem; position: sticky; top: 2rem; align-self: flex-start; font-size: 0.875rem; }
+nav ul { list-style: none; margin: 0; padding: 0; }
+nav li { margin: 0.25rem 0; }
+nav .level-3 { padding-left: 1rem; }
+nav .level-4, nav .level-5, nav .level-6 { padding-left: 2rem; }
+nav a { color: inherit; opacity: 0.75; text-decoration: none; }
+nav a:hover { opacity: 1; }
+nav .raw { display: block; margin-top: 1rem; }
+main { flex: 1; min-width: 0; }
+h1, h2, h3 { line-height: 1.25; }
+h1, h2 { border-bottom: 1px solid #8884; padding-bottom: 0.3em; }
+a { color: #0969da; }
+code { font: 0.875em ui-monospace, SFMono-Regular, Menlo, monospace; background: #8882; padding: 0.1em 0.3em; border-radius: 4px; }
+pre { padding: 1rem; overflow-x: auto; border-radius: 6px; border: 1px solid #8884; }
+pre code { background: none; padding: 0; }
+table { border-collapse: collapse; display: block; overflow-x: auto; }
+th, td { border: 1px solid #8886; padding: 0.4rem 0.8rem; }
+blockquote { margin: 0; padding: 0 1rem; border-left: 0.25rem solid #8886; opacity: 0.85; }
+img { max-width: 100%; }
+@media (max-width: 48rem) { .page { flex-direction: column; } nav { position: static; } }
+</style>
+</head>
+<body>
+<div class="page">
+<nav>
+{{- if .TOC}}
+<ul>
+{{- range .TOC}}
+<li class="level-{{.Level}}"><a href="#{{.ID}}">{{.Text}}</a></li>
+{{- end}}
+</ul>
+{{- end}}
+<a class="raw" href="{{.Source}}">View source</a>
+</nav>
+<main>
+{{.Body}}
+</main>
+</div>
+</body>
+</html>
+`))
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleMarkdown = "# Notes\n\nSee [other](other.md).\n\n## Setup `go`\n\n```go\nfunc main() {}\n```\n\n### Details\n\n| a | b |\n|---|---|\n| 1 | 2 |\n"
+
+func TestRenderMarkdownPage(t *testing.T) {
+	page, err := renderMarkdownPage([]byte(sampleMarkdown), "notes.md")
+	require.NoError(t, err)
+	html := string(page)
+
+	assert.Contains(t, html, "<title>Notes</title>")
+	assert.Contains(t, html, `<a href="other.md">other</a>`)
+	assert.Contains(t, html, `<li class="level-2"><a href="#setup-go">Setup go</a></li>`)
+	assert.Contains(t, html, `<li class="level-3"><a href="#details">Details</a></li>`)
+	assert.Contains(t, html, "<table>")
+	assert.Contains(t, html, `href="notes.md?raw"`)
+	// Code blocks are highlighted with inline styles
+	assert.Contains(t, html, `<pre style=`)
+	assert.Contains(t, html, `<span style=`)
+}
+
+func TestRenderMarkdownPageWithoutHeading(t *testing.T) {
+	page, err := renderMarkdownPage([]byte("just text"), "My Note.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "<title>My Note</title>")
+	assert.Contains(t, string(page), `href="My%20Note.md?raw"`)
+}
+
+func TestRenderMarkdownEscapesHTML(t *testing.T) {
+	page, err := renderMarkdownPage([]byte("<script>alert(1)</script>\n\n# <b>x</b>"), "x.md")
+	require.NoError(t, err)
+	assert.NotContains(t, string(page), "<script>alert(1)</script>")
+}
+
+func TestIsMarkdown(t *testing.T) {
+	assert.True(t, isMarkdown("/a/README.md"))
+	assert.True(t, isMarkdown("/notes.MARKDOWN"))
+	assert.False(t, isMarkdown("/a/readme.txt"))
+	assert.False(t, isMarkdown("/a/"))
+}
+
+func TestRenderMarkdownMiddleware(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"notes.md":        sampleMarkdown,
+		"docs/README.md":  "# Docs\n",
+		"site/index.html": "<p>site</p>",
+		"site/README.md":  "# Site\n",
+	})
+	root := http.Dir(dir)
+	handler := renderMarkdown(http.FileServer(root), root, true)
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("browser gets HTML", func(t *testing.T) {
+		rec := get("/notes.md", "text/html,application/xhtml+xml")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+		assert.Contains(t, rec.Body.String(), "<h1 id=\"notes\">Notes</h1>")
+	})
+
+	t.Run("other clients get the source", func(t *testing.T) {
+		rec := get("/notes.md", "*/*")
+		assert.Equal(t, sampleMarkdown, rec.Body.String())
+	})
+
+	t.Run("raw returns the source as text", func(t *testing.T) {
+		rec := get("/notes.md?raw", "text/html")
+		assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, sampleMarkdown, rec.Body.String())
+	})
+
+	t.Run("directory renders README", func(t *testing.T) {
+		rec := get("/docs/", "text/html")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "<title>Docs</title>")
+		assert.Contains(t, rec.Body.String(), `href="README.md?raw"`)
+	})
+
+	t.Run("index.html takes precedence over README", func(t *testing.T) {
+		rec := get("/site/", "text/html")
+		assert.Equal(t, "<p>site</p>", rec.Body.String())
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		rec := get("/missing.md", "text/html")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestRenderMarkdownListsDirectoriesWithoutIndex(t *testing.T) {
+	dir := writeTree(t, map[string]string{"docs/README.md": "# Docs\n"})
+	root := http.Dir(dir)
+	handler := renderMarkdown(http.FileServer(root), root, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/docs/", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<a href="README.md">README.md</a>`)
+	assert.NotContains(t, rec.Body.String(), "<title>Docs</title>")
+}
+
+func TestRenderMarkdownRespectsHiddenFiles(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".gitignore": "private.md\n",
+		"private.md": "# Private\n",
+	})
+	root := newHiddenFS(http.Dir(dir))
+	handler := renderMarkdown(http.FileServer(root), root, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/private.md", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the latency histogram.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// maxMetricLabels caps how many distinct paths and clients are tracked, so
+// that a scanner requesting random URLs cannot grow the metrics forever.
+// Anything beyond it is counted under "other".
+const maxMetricLabels = 1000
+
+type requestKey struct {
+	status int
+	path   string
+}
+
+// Metrics counts the requests served. It outlives handler reloads so that
+// counters keep going up.
+type Metrics struct {
+	started time.Time
+
+	mu       sync.Mutex
+	requests map[requestKey]uint64
+	paths    map[string]bool
+	clients  map[string]uint64
+	bytes    int64
+	buckets  []uint64
+	count    uint64
+	sum      float64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		started:  time.Now(),
+		requests: map[requestKey]uint64{},
+		paths:    map[string]bool{},
+		clients:  map[string]uint64{},
+		buckets:  make([]uint64, len(latencyBuckets)),
+	}
+}
+
+// observe records a finished request.
+func (m *Metrics) observe(r *http.Request, status int, bytes int64, d time.Duration) {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := r.URL.Path
+	if !m.paths[p] {
+		if len(m.paths) >= maxMetricLabels {
+			p = "other"
+		} else {
+			m.paths[p] = true
+		}
+	}
+	m.requests[requestKey{status: status, path: p}]++
+
+	if _, ok := m.clients[client]; !ok && len(m.clients) >= maxMetricLabels {
+		client = "other"
+	}
+	m.clients[client]++
+
+	m.bytes += bytes
+	seconds := d.Seconds()
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			m.buckets[i]++
+		}
+	}
+	m.count++
+	m.sum += seconds
+}
+
+// middleware records every request that passes through the handler, except
+// those for the admin endpoints themselves.
+func (m *Metrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, adminPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		lw := &loggingWriter{ResponseWriter: w}
+		defer func() {
+			status := lw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			m.observe(r, status, lw.bytes, time.Since(start))
+		}()
+		next.ServeHTTP(lw, r)
+	})
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writePrometheus writes the metrics in the Prometheus text format.
+func (m *Metrics) writePrometheus(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP serve_requests_total Requests served, by status code and path.")
+	fmt.Fprintln(w, "# TYPE serve_requests_total counter")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].path != keys[j].path {
+			return keys[i].path < keys[j].path
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "serve_requests_total{status=\"%d\",path=\"%s\"} %d\n", k.status, labelEscaper.Replace(k.path), m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP serve_client_requests_total Requests served, by client address.")
+	fmt.Fprintln(w, "# TYPE serve_client_requests_total counter")
+	clients := make([]string, 0, len(m.clients))
+	for c := range m.clients {
+		clients = append(clients, c)
+	}
+	sort.Strings(clients)
+	for _, c := range clients {
+		fmt.Fprintf(w, "serve_client_requests_total{client=\"%s\"} %d\n", labelEscaper.Replace(c), m.clients[c])
+	}
+
+	fmt.Fprintln(w, "# HELP serve_response_bytes_total Response body bytes sent.")
+	fmt.Fprintln(w, "# TYPE serve_response_bytes_total counter")
+	fmt.Fprintf(w, "serve_response_bytes_total %d\n", m.bytes)
+
+	fmt.Fprintln(w, "# HELP serve_request_duration_seconds Time taken to serve requests.")
+	fmt.Fprintln(w, "# TYPE serve_request_duration_seconds histogram")
+	for i, le := range latencyBuckets {
+		fmt.Fprintf(w, "serve_request_duration_seconds_bucket{le=\"%s\"} %d\n", strconv.FormatFloat(le, 'g', -1, 64), m.buckets[i])
+	}
+	fmt.Fprintf(w, "serve_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.count)
+	fmt.Fprintf(w, "serve_request_duration_seconds_sum %s\n", strconv.FormatFloat(m.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "serve_request_duration_seconds_count %d\n", m.count)
+
+	fmt.Fprintln(w, "# HELP serve_uptime_seconds Time since the server started.")
+	fmt.Fprintln(w, "# TYPE serve_uptime_seconds gauge")
+	fmt.Fprintf(w, "serve_uptime_seconds %s\n", strconv.FormatFloat(time.Since(m.started).Seconds(), 'f', 3, 64))
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	stats := NewMetrics()
+	handler := stats.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+
+	for _, target := range []string{"/", "/", "/missing", adminPrefix + "metrics"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var buf bytes.Buffer
+	stats.writePrometheus(&buf)
+	out := buf.String()
+	assert.Contains(t, out, `serve_requests_total{status="200",path="/"} 2`)
+	assert.Contains(t, out, `serve_requests_total{status="404",path="/missing"} 1`)
+	assert.NotContains(t, out, adminPrefix)
+	assert.Contains(t, out, `serve_client_requests_total{client="192.0.2.1"} 3`)
+	assert.Contains(t, out, "serve_response_bytes_total 29\n")
+	assert.Contains(t, out, `serve_request_duration_seconds_bucket{le="+Inf"} 3`)
+	assert.Contains(t, out, "serve_request_duration_seconds_count 3\n")
+	assert.Contains(t, out, "# TYPE serve_request_duration_seconds histogram")
+}
+
+func TestMetricsObserve(t *testing.T) {
+	stats := NewMetrics()
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	stats.observe(req, http.StatusOK, 10, 30*time.Millisecond)
+	stats.observe(req, http.StatusOK, 10, 3*time.Second)
+
+	var buf bytes.Buffer
+	stats.writePrometheus(&buf)
+	assert.Contains(t, buf.String(), `serve_request_duration_seconds_bucket{le="0.025"} 0`)
+	assert.Contains(t, buf.String(), `serve_request_duration_seconds_bucket{le="0.05"} 1`)
+	assert.Contains(t, buf.String(), `serve_request_duration_seconds_bucket{le="5"} 2`)
+	assert.Contains(t, buf.String(), "serve_request_duration_seconds_sum 3.03\n")
+}
+
+func TestMetricsCapPaths(t *testing.T) {
+	stats := NewMetrics()
+	for i := 0; i < maxMetricLabels+5; i++ {
+		stats.observe(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%d", i), nil), http.StatusNotFound, 0, 0)
+	}
+	stats.observe(httptest.NewRequest(http.MethodGet, `/quote"d`, nil), http.StatusNotFound, 0, 0)
+
+	var buf bytes.Buffer
+	stats.writePrometheus(&buf)
+	assert.Contains(t, buf.String(), `serve_requests_total{status="404",path="other"} 6`)
+	assert.Contains(t, buf.String(), `serve_requests_total{status="404",path="/0"} 1`)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+)
+
+// hookWriter calls before with the status code just before the response
+// headers are written, giving middleware a last chance to change them.
+type hookWriter struct {
+	http.ResponseWriter
+	before      func(status int)
+	wroteHeader bool
+}
+
+func (hw *hookWriter) WriteHeader(status int) {
+	if !hw.wroteHeader {
+		hw.wroteHeader = true
+		hw.before(status)
+	}
+	hw.ResponseWriter.WriteHeader(status)
+}
+
+func (hw *hookWriter) Write(b []byte) (int, error) {
+	if !hw.wroteHeader {
+		hw.WriteHeader(http.StatusOK)
+	}
+	return hw.ResponseWriter.Write(b)
+}
+
+// finish calls before if the handler returned without writing anything, in
+// which case the server sends 200 OK on its own. It is called once the
+// handler is done.
+func (hw *hookWriter) finish() {
+	if !hw.wroteHeader {
+		hw.wroteHeader = true
+		hw.before(http.StatusOK)
+	}
+}
+
+func (hw *hookWriter) Flush() {
+	if f, ok := hw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (hw *hookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(hw.ResponseWriter).Hijack()
+}
+
+func (hw *hookWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
+
+// extendWriteDeadline gives the response another timeout to write in. Long
+// responses which keep making progress, such as throttled files and
+// archives, call it before every write so that the server's write timeout
+// only cuts off clients which stall. Zero leaves the deadline alone.
+func extendWriteDeadline(w http.ResponseWriter, timeout time.Duration) {
+	if timeout > 0 {
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout))
+	}
+}
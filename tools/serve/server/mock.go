@@ -0,0 +1,298 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// mockMethods are the HTTP methods recognized in fixture file names.
+var mockMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// maxMockBody is the largest request body made available to templates.
+const maxMockBody = 1 << 20
+
+// fixture is a file parsed from the fixtures directory. A file named
+// users.GET.json has the stem "users", the method "GET" and the extension
+// ".json". A trailing .tmpl marks it as a template.
+type fixture struct {
+	file     string
+	stem     string
+	method   string
+	ext      string
+	template bool
+}
+
+// parseFixtureName splits a fixture file name into its parts. Sidecar files
+// are not fixtures.
+func parseFixtureName(name string) (fixture, bool) {
+	f := fixture{}
+	if base, ok := strings.CutSuffix(name, ".tmpl"); ok {
+		f.template = true
+		name = base
+	}
+
+	f.ext = path.Ext(name)
+	if f.ext == ".status" || f.ext == ".headers" {
+		return fixture{}, false
+	}
+	rest := strings.TrimSuffix(name, f.ext)
+	if i := strings.LastIndexByte(rest, '.'); i >= 0 && mockMethods[rest[i+1:]] {
+		f.method = rest[i+1:]
+		rest = rest[:i]
+	}
+	f.stem = rest
+	if f.stem == "" {
+		return fixture{}, false
+	}
+	return f, true
+}
+
+// param returns the name of a path parameter stem such as [id].
+func param(stem string) (string, bool) {
+	if strings.HasPrefix(stem, "[") && strings.HasSuffix(stem, "]") && len(stem) > 2 {
+		return stem[1 : len(stem)-1], true
+	}
+	return "", false
+}
+
+// mockMatch is a fixture chosen for a request along with the path parameters
+// captured on the way to it.
+type mockMatch struct {
+	fixture
+	params map[string]string
+}
+
+// resolveFixture finds the fixture for the request path segments below dir.
+// Literal names are preferred over [param] names, and files naming the
+// method are preferred over files which do not. Files without a method only
+// answer GET and HEAD.
+func resolveFixture(dir string, segments []string, method string, params map[string]string) (mockMatch, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return mockMatch{}, false
+	}
+	segment := segments[0]
+
+	if len(segments) > 1 {
+		// Literal directories first, then parameter directories.
+		for _, literal := range []bool{true, false} {
+			for _, e := range entries {
+				if !e.IsDir() {
+					continue
+				}
+				name, isParam := param(e.Name())
+				if literal == isParam || (literal && e.Name() != segment) {
+					continue
+				}
+				next := params
+				if isParam {
+					next = withParam(params, name, segment)
+				}
+				if m, ok := resolveFixture(filepath.Join(dir, e.Name()), segments[1:], method, next); ok {
+					return m, true
+				}
+			}
+		}
+		return mockMatch{}, false
+	}
+
+	var fixtures []fixture
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if f, ok := parseFixtureName(e.Name()); ok {
+			f.file = filepath.Join(dir, e.Name())
+			fixtures = append(fixtures, f)
+		}
+	}
+
+	allowsDefault := method == http.MethodGet || method == http.MethodHead
+	for _, literal := range []bool{true, false} {
+		for _, withMethod := range []bool{true, false} {
+			for _, f := range fixtures {
+				name, isParam := param(f.stem)
+				if literal == isParam || (literal && f.stem != segment) {
+					continue
+				}
+				if withMethod && f.method != method && !(method == http.MethodHead && f.method == http.MethodGet) {
+					continue
+				}
+				if !withMethod && (f.method != "" || !allowsDefault) {
+					continue
+				}
+				m := mockMatch{fixture: f, params: params}
+				if isParam {
+					m.params = withParam(params, name, segment)
+				}
+				return m, true
+			}
+		}
+	}
+
+	// A directory can answer for itself with an index fixture.
+	if info, err := os.Stat(filepath.Join(dir, segment)); err == nil && info.IsDir() {
+		return resolveFixture(filepath.Join(dir, segment), []string{"index"}, method, params)
+	}
+	return mockMatch{}, false
+}
+
+// withParam returns a copy of params with one more parameter.
+func withParam(params map[string]string, name, value string) map[string]string {
+	next := make(map[string]string, len(params)+1)
+	for k, v := range params {
+		next[k] = v
+	}
+	next[name] = value
+	return next
+}
+
+// mockRequest is the data available to templated fixtures.
+type mockRequest struct {
+	Method  string
+	Path    string
+	Params  map[string]string
+	Query   map[string]string
+	Headers map[string]string
+	Body    string
+	// JSON is the request body decoded as JSON, if it is valid JSON.
+	JSON any
+}
+
+var mockFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// mockAPI wraps the handler so that requests matching a fixture in dir are
+// answered from it. Everything else falls through to the file server.
+func mockAPI(next http.Handler, dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		segments := strings.Split(strings.Trim(path.Clean("/"+r.URL.Path), "/"), "/")
+		if segments[0] == "" {
+			segments = []string{"index"}
+		}
+
+		m, ok := resolveFixture(dir, segments, r.Method, map[string]string{})
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := serveFixture(w, r, m); err != nil {
+			http.Error(w, fmt.Sprintf("Error serving fixture %s: %s", m.file, err), http.StatusInternalServerError)
+		}
+	})
+}
+
+// serveFixture writes the fixture, its sidecar status and headers to w.
+func serveFixture(w http.ResponseWriter, r *http.Request, m mockMatch) error {
+	body, err := os.ReadFile(m.file)
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(strings.TrimSuffix(m.file, ".tmpl"), m.ext)
+	status := http.StatusOK
+	if b, err := os.ReadFile(base + ".status"); err == nil {
+		status, err = strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil {
+			return fmt.Errorf("invalid status file: %w", err)
+		}
+	}
+
+	if ctype := mime.TypeByExtension(m.ext); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+	if f, err := os.Open(base + ".headers"); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			name, value, err := ParseHeader(line)
+			if err != nil {
+				f.Close()
+				return fmt.Errorf("invalid headers file: %w", err)
+			}
+			w.Header().Add(name, value)
+		}
+		f.Close()
+	}
+
+	if m.template {
+		body, err = renderFixture(body, r, m)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+	return nil
+}
+
+// renderFixture executes a templated fixture against the request.
+func renderFixture(source []byte, r *http.Request, m mockMatch) ([]byte, error) {
+	tmpl, err := template.New(filepath.Base(m.file)).Funcs(mockFuncs).Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	reqBody, err := io.ReadAll(io.LimitReader(r.Body, maxMockBody))
+	if err != nil {
+		return nil, err
+	}
+
+	data := mockRequest{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Params:  m.params,
+		Query:   map[string]string{},
+		Headers: map[string]string{},
+		Body:    string(reqBody),
+	}
+	for k := range r.URL.Query() {
+		data.Query[k] = r.URL.Query().Get(k)
+	}
+	for k := range r.Header {
+		data.Headers[k] = r.Header.Get(k)
+	}
+	if len(reqBody) > 0 {
+		var decoded any
+		if json.Unmarshal(reqBody, &decoded) == nil {
+			data.JSON = decoded
+		}
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFixtureName(t *testing.T) {
+	f, ok := parseFixtureName("users.GET.json")
+	require.True(t, ok)
+	assert.Equal(t, fixture{stem: "users", method: "GET", ext: ".json"}, f)
+
+	f, ok = parseFixtureName("[id].json.tmpl")
+	require.True(t, ok)
+	assert.Equal(t, fixture{stem: "[id]", ext: ".json", template: true}, f)
+
+	f, ok = parseFixtureName("v1.2.json")
+	require.True(t, ok)
+	assert.Equal(t, "v1.2", f.stem)
+
+	for _, sidecar := range []string{"users.GET.status", "users.headers"} {
+		_, ok := parseFixtureName(sidecar)
+		assert.False(t, ok, sidecar)
+	}
+}
+
+func TestMockAPI(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"api/users.GET.json":        `[{"id":1}]`,
+		"api/users.POST.json":       `{"created":true}`,
+		"api/users.POST.status":     "201\n",
+		"api/users.POST.headers":    "# sent on create\nLocation: /api/users/1\n",
+		"api/users/me.json":         `{"id":"me"}`,
+		"api/users/[id].json.tmpl":  `{"id":"{{.Params.id}}","q":"{{.Query.q}}"}`,
+		"api/users/[id]/posts.json": `[]`,
+		"api/echo.POST.json.tmpl":   `{{json .JSON}}`,
+		"api/health/index.txt":      "healthy",
+		"api/broken.GET.json":       "{}",
+		"api/broken.GET.status":     "teapot",
+	})
+	handler := mockAPI(okHandler, dir)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/api/users", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `[{"id":1}]`, rec.Body.String())
+
+	rec = serve(http.MethodHead, "/api/users", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = serve(http.MethodPost, "/api/users", "")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/api/users/1", rec.Header().Get("Location"))
+
+	rec = serve(http.MethodGet, "/api/users/me", "")
+	assert.Equal(t, `{"id":"me"}`, rec.Body.String())
+
+	rec = serve(http.MethodGet, "/api/users/42?q=x", "")
+	assert.Equal(t, `{"id":"42","q":"x"}`, rec.Body.String())
+
+	rec = serve(http.MethodGet, "/api/users/42/posts", "")
+	assert.Equal(t, "[]", rec.Body.String())
+
+	rec = serve(http.MethodPost, "/api/echo", `{"name":"ada"}`)
+	assert.Equal(t, `{"name":"ada"}`, rec.Body.String())
+
+	rec = serve(http.MethodGet, "/api/health", "")
+	assert.Equal(t, "healthy", rec.Body.String())
+
+	rec = serve(http.MethodGet, "/api/broken", "")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	// Fixtures without a method only answer GET and HEAD.
+	rec = serve(http.MethodDelete, "/api/users/42", "")
+	assert.Equal(t, "ok", rec.Body.String())
+
+	// Anything else falls through to the file server.
+	rec = serve(http.MethodGet, "/index.html", "")
+	assert.Equal(t, "ok", rec.Body.String())
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Mount serves a directory under a URL prefix.
+type Mount struct {
+	Prefix string
+	Dir    string
+}
+
+// ParseMount parses a /prefix=dir flag such as "/docs=./site".
+func ParseMount(s string) (Mount, error) {
+	prefix, dir, ok := strings.Cut(s, "=")
+	prefix, dir = strings.TrimSpace(prefix), strings.TrimSpace(dir)
+	if !ok || prefix == "" || dir == "" {
+		return Mount{}, fmt.Errorf("mount must be in the form /prefix=dir, got %q", s)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Mount{}, fmt.Errorf("error mounting %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return Mount{}, fmt.Errorf("error mounting %s: not a directory", dir)
+	}
+
+	return Mount{Prefix: CleanPrefix(prefix), Dir: dir}, nil
+}
+
+// CleanPrefix normalizes a URL prefix to start with a slash and, unless it
+// is the root, not end with one.
+func CleanPrefix(prefix string) string {
+	return path.Clean("/" + prefix)
+}
+
+type routedMount struct {
+	Mount
+	handler http.Handler
+}
+
+// mountRouter sends each request to the mount with the longest matching
+// prefix. The root lists the mounts unless something is mounted there.
+type mountRouter struct {
+	mounts []routedMount
+}
+
+// newMountRouter returns a router for the mounts. newHandler builds the
+// handler which serves a mount's directory. If two mounts share a prefix,
+// the first one wins.
+func newMountRouter(mounts []Mount, newHandler func(dir string) http.Handler) *mountRouter {
+	router := &mountRouter{}
+	for _, m := range mounts {
+		h := newHandler(m.Dir)
+		if m.Prefix != "/" {
+			h = http.StripPrefix(m.Prefix, h)
+		}
+		router.mounts = append(router.mounts, routedMount{Mount: m, handler: h})
+	}
+
+	sort.SliceStable(router.mounts, func(i, j int) bool {
+		return len(router.mounts[i].Prefix) > len(router.mounts[j].Prefix)
+	})
+	return router
+}
+
+func (router *mountRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, m := range router.mounts {
+		switch {
+		case m.Prefix == "/" || strings.HasPrefix(r.URL.Path, m.Prefix+"/"):
+			m.handler.ServeHTTP(w, r)
+			return
+		case r.URL.Path == m.Prefix:
+			target := m.Prefix + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+	}
+
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	mounts := make([]Mount, len(router.mounts))
+	for i, m := range router.mounts {
+		mounts[i] = m.Mount
+	}
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].Prefix < mounts[j].Prefix })
+	mountIndexTemplate.Execute(w, mounts)
+}
+
+var mountIndexTemplate = template.Must(template.New("mounts").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Mounts</title>
+</head>
+<body>
+<h1>Mounts</h1>
+<ul>
+{{- range .}}
+<li><a href="{{.Prefix}}/">{{.Prefix}}/</a> &rarr; <code>{{.Dir}}</code></li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
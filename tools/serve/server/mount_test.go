@@ -0,0 +1,110 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMount(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := ParseMount("docs/=" + dir)
+	require.NoError(t, err)
+	assert.Equal(t, Mount{Prefix: "/docs", Dir: dir}, m)
+
+	m, err = ParseMount("/=" + dir)
+	require.NoError(t, err)
+	assert.Equal(t, "/", m.Prefix)
+
+	for _, invalid := range []string{"/docs", "=" + dir, "/docs=", "/docs=" + filepath.Join(dir, "missing")} {
+		_, err := ParseMount(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestCleanPrefix(t *testing.T) {
+	assert.Equal(t, "/", CleanPrefix("/"))
+	assert.Equal(t, "/", CleanPrefix(""))
+	assert.Equal(t, "/docs", CleanPrefix("docs"))
+	assert.Equal(t, "/a/b", CleanPrefix("/a/b/"))
+}
+
+func TestMountRouter(t *testing.T) {
+	site := writeTree(t, map[string]string{"index.txt": "site", "sub/page.txt": "site page"})
+	assets := writeTree(t, map[string]string{"logo.svg": "<svg/>"})
+	nested := writeTree(t, map[string]string{"page.txt": "nested page"})
+
+	router := newMountRouter([]Mount{
+		{Prefix: "/docs", Dir: site},
+		{Prefix: "/assets", Dir: assets},
+		{Prefix: "/docs/sub", Dir: nested},
+	}, func(dir string) http.Handler {
+		return http.FileServer(http.Dir(dir))
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("/docs/index.txt")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "site", body)
+
+	status, body = get("/assets/logo.svg")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "<svg/>", body)
+
+	// The longest prefix wins
+	status, body = get("/docs/sub/page.txt")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "nested page", body)
+
+	// A prefix without a trailing slash redirects to the directory
+	status, body = get("/assets")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "logo.svg")
+
+	// Prefixes only match whole path segments
+	status, _ = get("/assetsx/logo.svg")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// The root lists the mounts
+	status, body = get("/")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `<a href="/assets/">/assets/</a>`)
+	assert.Contains(t, body, `<a href="/docs/">/docs/</a>`)
+	assert.Contains(t, body, `<a href="/docs/sub/">/docs/sub/</a>`)
+}
+
+func TestMountRouterRootMount(t *testing.T) {
+	root := writeTree(t, map[string]string{"index.txt": "root"})
+	docs := writeTree(t, map[string]string{"index.txt": "docs"})
+
+	router := newMountRouter([]Mount{
+		{Prefix: "/", Dir: root},
+		{Prefix: "/docs", Dir: docs},
+	}, func(dir string) http.Handler {
+		return http.FileServer(http.Dir(dir))
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index.txt", nil))
+	assert.Equal(t, "root", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/index.txt", nil))
+	assert.Equal(t, "docs", rec.Body.String())
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// preloadLinks finds the stylesheets and scripts referenced by HTML pages and
+// announces them with Link preload headers, so browsers can fetch them
+// without waiting for the page to be parsed. Links are cached until the
+// page's size or modification time changes.
+type preloadLinks struct {
+	root    http.FileSystem
+	mu      sync.Mutex
+	entries map[string]preloadEntry
+}
+
+type preloadEntry struct {
+	modTime time.Time
+	size    int64
+	links   []string
+}
+
+func newPreloadLinks(root http.FileSystem) *preloadLinks {
+	return &preloadLinks{root: root, entries: map[string]preloadEntry{}}
+}
+
+// links returns the Link header values for the named page, following
+// directories to their index.html.
+func (p *preloadLinks) links(name string) []string {
+	if strings.HasSuffix(name, "/") {
+		name += "index.html"
+	}
+	if ext := path.Ext(name); ext != ".html" && ext != ".htm" {
+		return nil
+	}
+
+	f, err := p.root.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return nil
+	}
+
+	p.mu.Lock()
+	entry, ok := p.entries[name]
+	p.mu.Unlock()
+	if ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.links
+	}
+
+	links := parsePreloadLinks(f)
+	p.mu.Lock()
+	p.entries[name] = preloadEntry{modTime: info.ModTime(), size: info.Size(), links: links}
+	p.mu.Unlock()
+	return links
+}
+
+// middleware adds the Link headers before the file server runs.
+func (p *preloadLinks) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			for _, link := range p.links(r.URL.Path) {
+				w.Header().Add("Link", link)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parsePreloadLinks returns Link header values for the same-origin
+// stylesheets and scripts in the HTML. Relative URLs are kept as they are,
+// since browsers resolve them against the page just like the HTML would be.
+func parsePreloadLinks(r io.Reader) []string {
+	var links []string
+	seen := map[string]bool{}
+	add := func(ref, params string) {
+		u, err := url.Parse(strings.TrimSpace(ref))
+		if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+			return
+		}
+		link := "<" + u.String() + ">; " + params
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			attrs := map[string]string{}
+			for _, a := range tok.Attr {
+				attrs[a.Key] = a.Val
+			}
+
+			switch tok.Data {
+			case "base":
+				// Relative URLs would no longer resolve the way the page does.
+				if _, ok := attrs["href"]; ok {
+					return nil
+				}
+			case "link":
+				if strings.EqualFold(attrs["rel"], "stylesheet") {
+					add(attrs["href"], "rel=preload; as=style")
+				}
+			case "script":
+				if src, ok := attrs["src"]; ok {
+					if attrs["type"] == "module" {
+						add(src, "rel=modulepreload")
+					} else {
+						add(src, "rel=preload; as=script")
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePreloadLinks(t *testing.T) {
+	links := parsePreloadLinks(strings.NewReader(`<!doctype html>
+<html>
+<head>
+  <link rel="stylesheet" href="/style.css">
+  <link rel="stylesheet" href="https://cdn.example.com/lib.css">
+  <link rel="icon" href="/favicon.ico">
+  <script src="vendor.js"></script>
+  <script type="module" src="./main.js"></script>
+  <script src="//cdn.example.com/lib.js"></script>
+  <script>console.log("inline")</script>
+</head>
+<body><script src="vendor.js"></script></body>
+</html>`))
+
+	assert.Equal(t, []string{
+		"</style.css>; rel=preload; as=style",
+		"<vendor.js>; rel=preload; as=script",
+		"<./main.js>; rel=modulepreload",
+	}, links)
+
+	assert.Empty(t, parsePreloadLinks(strings.NewReader(`<base href="/app/"><script src="main.js"></script>`)))
+}
+
+func TestPreloadLinksMiddleware(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"index.html":      `<script src="/app.js"></script>`,
+		"docs/guide.html": `<link rel="stylesheet" href="guide.css">`,
+		"app.js":          `console.log("app")`,
+	})
+	handler := newPreloadLinks(http.Dir(dir)).middleware(okHandler)
+
+	get := func(target string) []string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Header().Values("Link")
+	}
+
+	assert.Equal(t, []string{"</app.js>; rel=preload; as=script"}, get("/"))
+	assert.Equal(t, []string{"<guide.css>; rel=preload; as=style"}, get("/docs/guide.html"))
+	assert.Empty(t, get("/app.js"))
+	assert.Empty(t, get("/docs/"))
+
+	// Changes to the page are picked up.
+	page := filepath.Join(dir, "index.html")
+	require.NoError(t, os.WriteFile(page, []byte(`<script src="/other.js"></script>`), 0644))
+	require.NoError(t, os.Chtimes(page, time.Now(), time.Now().Add(time.Hour)))
+	assert.Equal(t, []string{"</other.js>; rel=preload; as=script"}, get("/"))
+}
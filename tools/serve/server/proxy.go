@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ProxyRoute forwards requests under a URL prefix to another server.
+type ProxyRoute struct {
+	Prefix string
+	Target *url.URL
+}
+
+// ParseProxy parses a /prefix=url flag such as "/api=http://localhost:3000".
+func ParseProxy(s string) (ProxyRoute, error) {
+	prefix, target, ok := strings.Cut(s, "=")
+	prefix, target = strings.TrimSpace(prefix), strings.TrimSpace(target)
+	if !ok || prefix == "" || target == "" {
+		return ProxyRoute{}, fmt.Errorf("proxy must be in the form /prefix=url, got %q", s)
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ProxyRoute{}, fmt.Errorf("invalid proxy target %q, expected something like http://localhost:3000", target)
+	}
+	return ProxyRoute{Prefix: CleanPrefix(prefix), Target: u}, nil
+}
+
+// serveCredentials are the credentials which serve checks itself. Proxies
+// remove them so that they do not leak to other servers.
+type serveCredentials struct {
+	// BasicAuth is the Authorization header of HTTP basic auth.
+	BasicAuth bool
+	// Token is the access token cookie and query parameter.
+	Token bool
+}
+
+// strip removes the credentials from a request about to be forwarded.
+func (c serveCredentials) strip(r *http.Request) {
+	if c.BasicAuth {
+		r.Header.Del("Authorization")
+	}
+	if !c.Token {
+		return
+	}
+
+	if query := r.URL.Query(); query.Has(tokenParam) {
+		query.Del(tokenParam)
+		r.URL.RawQuery = query.Encode()
+	}
+	cookies := r.Cookies()
+	if !slices.ContainsFunc(cookies, func(c *http.Cookie) bool { return c.Name == tokenCookie }) {
+		return
+	}
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != tokenCookie {
+			r.AddCookie(c)
+		}
+	}
+}
+
+// withProxies wraps the handler so that requests under a proxied prefix are
+// forwarded with their full path, like the dev servers of frontend tools.
+// The longest matching prefix wins. The credentials of serve are removed
+// from forwarded requests.
+func withProxies(next http.Handler, routes []ProxyRoute, credentials serveCredentials) http.Handler {
+	routes = append([]ProxyRoute(nil), routes...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].Prefix) > len(routes[j].Prefix)
+	})
+
+	proxies := make([]http.Handler, len(routes))
+	for i, route := range routes {
+		proxies[i] = &httputil.ReverseProxy{
+			Rewrite: func(r *httputil.ProxyRequest) {
+				credentials.strip(r.Out)
+				r.SetURL(route.Target)
+				r.SetXForwarded()
+			},
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, route := range routes {
+			if route.Prefix == "/" || r.URL.Path == route.Prefix || strings.HasPrefix(r.URL.Path, route.Prefix+"/") {
+				proxies[i].ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
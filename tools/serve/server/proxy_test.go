@@ -0,0 +1,84 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProxy(t *testing.T) {
+	route, err := ParseProxy("api=http://localhost:3000")
+	require.NoError(t, err)
+	assert.Equal(t, "/api", route.Prefix)
+	assert.Equal(t, "localhost:3000", route.Target.Host)
+
+	for _, invalid := range []string{"/api", "/api=", "/api=localhost:3000", "/api=ftp://host"} {
+		_, err := ParseProxy(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestWithProxies(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "backend "+r.URL.RequestURI()+" "+r.Header.Get("X-Forwarded-Host"))
+	}))
+	defer backend.Close()
+
+	route, err := ParseProxy("/api=" + backend.URL)
+	require.NoError(t, err)
+	handler := withProxies(okHandler, []ProxyRoute{route}, serveCredentials{})
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Body.String()
+	}
+
+	assert.Equal(t, "backend /api/users?page=2 example.com", get("/api/users?page=2"))
+	assert.Equal(t, "backend /api example.com", get("/api"))
+	assert.Equal(t, "ok", get("/apiary"))
+	assert.Equal(t, "ok", get("/index.html"))
+}
+
+func TestProxiesStripServeCredentials(t *testing.T) {
+	var got *http.Request
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	defer backend.Close()
+
+	route, err := ParseProxy("/api=" + backend.URL)
+	require.NoError(t, err)
+	tok, err := NewAccessToken(0)
+	require.NoError(t, err)
+
+	forward := func(opts Options) *http.Request {
+		opts.Proxies = []ProxyRoute{route}
+		opts.Token = tok
+		opts.User, opts.Password = "admin", "hunter2"
+
+		req := httptest.NewRequest(http.MethodGet, "/api/users?page=2&token="+tok.Value, nil)
+		req.SetBasicAuth("admin", "hunter2")
+		req.AddCookie(&http.Cookie{Name: tokenCookie, Value: tok.Value})
+		req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+		rec := httptest.NewRecorder()
+		NewHandler(opts).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		return got
+	}
+
+	r := forward(Options{})
+	assert.Empty(t, r.Header.Get("Authorization"))
+	assert.Equal(t, "page=2", r.URL.RawQuery)
+	assert.Equal(t, "session=abc", r.Header.Get("Cookie"))
+
+	r = forward(Options{ProxyCredentials: true})
+	assert.NotEmpty(t, r.Header.Get("Authorization"))
+	assert.Equal(t, tok.Value, r.URL.Query().Get("token"))
+	_, err = r.Cookie(tokenCookie)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateUnits are the suffixes accepted by parseRate, in bytes.
+var rateUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"gib", 1 << 30}, {"mib", 1 << 20}, {"kib", 1 << 10},
+	{"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10},
+	{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10},
+	{"b", 1},
+}
+
+// ParseRate parses a bandwidth such as "500KB/s" or "2MB" into bytes per
+// second. Units are powers of 1024.
+func ParseRate(s string) (float64, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	v = strings.TrimSuffix(v, "/s")
+
+	multiplier := 1.0
+	for _, unit := range rateUnits {
+		if strings.HasSuffix(v, unit.suffix) {
+			v = strings.TrimSuffix(v, unit.suffix)
+			multiplier = unit.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid rate %q, expected something like 500KB/s", s)
+	}
+	return n * multiplier, nil
+}
+
+// RateLimiter paces writes so that they average out to a fixed rate. It is
+// safe for concurrent use, so one limiter can be shared by many requests.
+type RateLimiter struct {
+	rate float64
+	mu   sync.Mutex
+	next time.Time
+}
+
+func NewRateLimiter(bytesPerSecond float64) *RateLimiter {
+	return &RateLimiter{rate: bytesPerSecond}
+}
+
+// wait blocks until n more bytes may be sent or the context is done.
+func (l *RateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+
+	return sleep(ctx, delay)
+}
+
+// chunkSize is how many bytes to send between waits, which keeps slow
+// rates smooth instead of bursty.
+func (l *RateLimiter) chunkSize() int {
+	return min(max(int(l.rate/10), 512), 32<<10)
+}
+
+// sleep pauses for d or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type connLimiterKey struct{}
+
+// connLimiterSlot holds the limiter of a single connection. It is created
+// on first use because the rate is only known to the handler.
+type connLimiterSlot struct {
+	once    sync.Once
+	limiter *RateLimiter
+}
+
+// ConnContext gives every connection its own limiter slot. It is used as
+// http.Server.ConnContext.
+func ConnContext(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connLimiterKey{}, &connLimiterSlot{})
+}
+
+// ThrottleOptions controls bandwidth throttling.
+type ThrottleOptions struct {
+	// Global is shared by every request. Nil disables it.
+	Global *RateLimiter
+	// PerConn is the rate of each connection in bytes per second. Zero
+	// disables it.
+	PerConn float64
+}
+
+// throttle wraps the handler so that response bodies are sent no faster than
+// the configured rates. Every chunk gets writeTimeout to be written in.
+func throttle(next http.Handler, opts ThrottleOptions, writeTimeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var limiters []*RateLimiter
+		if opts.Global != nil {
+			limiters = append(limiters, opts.Global)
+		}
+		if opts.PerConn > 0 {
+			slot, ok := r.Context().Value(connLimiterKey{}).(*connLimiterSlot)
+			if !ok {
+				slot = &connLimiterSlot{}
+			}
+			slot.once.Do(func() { slot.limiter = NewRateLimiter(opts.PerConn) })
+			limiters = append(limiters, slot.limiter)
+		}
+		if len(limiters) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(&throttledWriter{ResponseWriter: w, ctx: r.Context(), limiters: limiters, timeout: writeTimeout}, r)
+	})
+}
+
+// throttledWriter writes the body in small chunks, waiting on every limiter
+// before each one.
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	limiters []*RateLimiter
+	timeout  time.Duration
+}
+
+func (tw *throttledWriter) Write(b []byte) (int, error) {
+	chunk := tw.limiters[0].chunkSize()
+	for _, l := range tw.limiters[1:] {
+		chunk = min(chunk, l.chunkSize())
+	}
+
+	written := 0
+	for written < len(b) {
+		n := min(chunk, len(b)-written)
+		for _, l := range tw.limiters {
+			if err := l.wait(tw.ctx, n); err != nil {
+				return written, err
+			}
+		}
+		extendWriteDeadline(tw.ResponseWriter, tw.timeout)
+		m, err := tw.ResponseWriter.Write(b[written : written+n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	return written, nil
+}
+
+func (tw *throttledWriter) Flush() {
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (tw *throttledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// LatencyRule delays responses for paths matching a glob.
+type LatencyRule struct {
+	// Pattern is a glob matched like cache rules.
+	Pattern string
+	Delay   time.Duration
+}
+
+// ParseLatencyRule parses a glob=duration flag such as "/api/*=200ms".
+func ParseLatencyRule(s string) (LatencyRule, error) {
+	pattern, value, ok := strings.Cut(s, "=")
+	pattern, value = strings.TrimSpace(pattern), strings.TrimSpace(value)
+	if !ok || pattern == "" {
+		return LatencyRule{}, fmt.Errorf("latency must be in the form glob=duration, got %q", s)
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		return LatencyRule{}, fmt.Errorf("invalid latency %q: %w", value, err)
+	}
+	return LatencyRule{Pattern: pattern, Delay: delay}, nil
+}
+
+// withLatency wraps the handler so that requests matching a rule wait before
+// being served. The first matching rule wins.
+func withLatency(next http.Handler, rules []LatencyRule) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, rule := range rules {
+			if globMatch(rule.Pattern, r.URL.Path) {
+				if err := sleep(r.Context(), rule.Delay); err != nil {
+					return
+				}
+				break
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
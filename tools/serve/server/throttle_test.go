@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"500KB/s", 500 * 1024},
+		{"500kb", 500 * 1024},
+		{"1.5MB/s", 1.5 * 1024 * 1024},
+		{"2MiB/s", 2 * 1024 * 1024},
+		{"1G", 1 << 30},
+		{"100B/s", 100},
+		{"100", 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rate, err := ParseRate(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, rate)
+		})
+	}
+
+	for _, invalid := range []string{"", "fast", "0KB/s", "-1MB", "KB/s"} {
+		_, err := ParseRate(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRateLimiterPacesWrites(t *testing.T) {
+	l := NewRateLimiter(100 * 1024)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, l.wait(context.Background(), 5*1024))
+	}
+	elapsed := time.Since(start)
+
+	// 20KB at 100KB/s takes 200ms
+	assert.GreaterOrEqual(t, elapsed, 180*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+}
+
+func TestRateLimiterCancelled(t *testing.T) {
+	l := NewRateLimiter(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, l.wait(ctx, 1024))
+}
+
+func TestThrottleLimitsResponses(t *testing.T) {
+	body := strings.Repeat("x", 20*1024)
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	})
+
+	for name, opts := range map[string]ThrottleOptions{
+		"global":         {Global: NewRateLimiter(100 * 1024)},
+		"per connection": {PerConn: 100 * 1024},
+	} {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			rec := httptest.NewRecorder()
+			throttle(inner, opts, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, body, rec.Body.String())
+			assert.GreaterOrEqual(t, time.Since(start), 180*time.Millisecond)
+		})
+	}
+}
+
+func TestThrottleOutlastsWriteTimeout(t *testing.T) {
+	body := strings.Repeat("x", 10*1024)
+	dir := writeTree(t, map[string]string{"big.txt": body})
+	handler := NewHandler(Options{
+		Mounts:       []Mount{{Prefix: "/", Dir: dir}},
+		Throttle:     ThrottleOptions{PerConn: 20 * 1024},
+		WriteTimeout: 200 * time.Millisecond,
+	})
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Config.ConnContext = ConnContext
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/big.txt")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(got))
+}
+
+func TestThrottlePerConnectionSharesLimiter(t *testing.T) {
+	slot := &connLimiterSlot{}
+	ctx := context.WithValue(context.Background(), connLimiterKey{}, slot)
+
+	handler := throttle(okHandler, ThrottleOptions{PerConn: 1024}, 0)
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	}
+	require.NotNil(t, slot.limiter)
+	assert.Equal(t, 1024.0, slot.limiter.rate)
+}
+
+func TestParseLatencyRule(t *testing.T) {
+	rule, err := ParseLatencyRule("/api/*=200ms")
+	require.NoError(t, err)
+	assert.Equal(t, LatencyRule{Pattern: "/api/*", Delay: 200 * time.Millisecond}, rule)
+
+	for _, invalid := range []string{"/api/*", "=1s", "/api/*=soon"} {
+		_, err := ParseLatencyRule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestWithLatency(t *testing.T) {
+	handler := withLatency(okHandler, []LatencyRule{
+		{Pattern: "*.js", Delay: 150 * time.Millisecond},
+		{Pattern: "/slow/*", Delay: time.Hour},
+	})
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	assert.Equal(t, "ok", rec.Body.String())
+
+	start = time.Now()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// A client that goes away stops waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow/x", nil).WithContext(ctx))
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestRangeRequests(t *testing.T) {
+	dir := writeTree(t, map[string]string{"data.txt": "0123456789"})
+	handler := newFileHandler(Options{Precompressed: true, Markdown: true, Archives: true}, dir)
+
+	t.Run("single range", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/data.txt", nil)
+		req.Header.Set("Range", "bytes=2-5")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusPartialContent, rec.Code)
+		assert.Equal(t, "2345", rec.Body.String())
+		assert.Equal(t, "bytes 2-5/10", rec.Header().Get("Content-Range"))
+	})
+
+	t.Run("multiple ranges", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/data.txt", nil)
+		req.Header.Set("Range", "bytes=0-1,7-")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusPartialContent, rec.Code)
+		mediaType, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/byteranges", mediaType)
+
+		mr := multipart.NewReader(rec.Body, params["boundary"])
+		var parts []string
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			b, _ := io.ReadAll(p)
+			parts = append(parts, string(b))
+		}
+		assert.Equal(t, []string{"01", "789"}, parts)
+	})
+}
+
+func TestIfRangeDetectsChangedFiles(t *testing.T) {
+	dir := writeTree(t, map[string]string{"data.txt": "0123456789"})
+	handler := newFileHandler(Options{Precompressed: true, Markdown: true, Archives: true}, dir)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data.txt", nil))
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.False(t, strings.HasPrefix(etag, "W/"))
+
+	resume := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/data.txt", nil)
+		req.Header.Set("Range", "bytes=5-")
+		req.Header.Set("If-Range", etag)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Unchanged file resumes
+	rec = resume()
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "56789", rec.Body.String())
+
+	// Rewriting the file within the same second still invalidates the range
+	path := filepath.Join(dir, "data.txt")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("abcdefghij"), 0644))
+	require.NoError(t, os.Chtimes(path, info.ModTime(), info.ModTime().Add(time.Millisecond)))
+
+	rec = resume()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "abcdefghij", rec.Body.String())
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/webdav"
+)
+
+// webdavReadMethods are answered by WebDAV in both modes. Everything else
+// that is not a write method, including GET, goes to the file server.
+var webdavReadMethods = map[string]bool{
+	"PROPFIND":         true,
+	http.MethodOptions: true,
+}
+
+// webdavWriteMethods are answered by WebDAV in read-write mode and refused
+// otherwise.
+var webdavWriteMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodDelete: true,
+	"MKCOL":           true,
+	"COPY":            true,
+	"MOVE":            true,
+	"PROPPATCH":       true,
+	"LOCK":            true,
+	"UNLOCK":          true,
+}
+
+// WebDAVOptions controls the WebDAV endpoints.
+type WebDAVOptions struct {
+	// Write allows clients to change files.
+	Write bool
+}
+
+type webdavRoute struct {
+	prefix  string
+	handler http.Handler
+}
+
+// withWebDAV wraps the handler so that WebDAV clients can browse, and
+// optionally change, each mount under its prefix. Plain GET requests keep
+// going to the file server. Hidden files stay hidden unless all is set.
+func withWebDAV(next http.Handler, mounts []Mount, opts WebDAVOptions, all bool) http.Handler {
+	var routes []webdavRoute
+	for _, m := range mounts {
+		dav := &davFS{FileSystem: webdav.Dir(m.Dir), readOnly: !opts.Write}
+		if !all {
+			dav.hidden = newHiddenFS(http.Dir(m.Dir))
+		}
+		prefix := m.Prefix
+		if prefix == "/" {
+			prefix = ""
+		}
+		routes = append(routes, webdavRoute{prefix: m.Prefix, handler: &webdav.Handler{
+			Prefix:     prefix,
+			FileSystem: dav,
+			LockSystem: webdav.NewMemLS(),
+		}})
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].prefix) > len(routes[j].prefix)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		write := webdavWriteMethods[r.Method]
+		if !write && !webdavReadMethods[r.Method] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if write && !opts.Write {
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS, PROPFIND")
+			http.Error(w, "WebDAV is read-only, restart with --webdav-write to allow changes", http.StatusMethodNotAllowed)
+			return
+		}
+
+		for _, route := range routes {
+			if route.prefix == "/" || r.URL.Path == route.prefix || strings.HasPrefix(r.URL.Path, route.prefix+"/") {
+				route.handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+}
+
+// davFS exposes a directory over WebDAV. Hidden paths do not exist and
+// cannot be created, and a read-only file system refuses every change.
+type davFS struct {
+	webdav.FileSystem
+	hidden   *hiddenFS
+	readOnly bool
+}
+
+// check returns an error if the path is hidden. Existing hidden paths do
+// not exist, and new ones are not allowed.
+func (d *davFS) check(ctx context.Context, name string) error {
+	if d.hidden == nil {
+		return nil
+	}
+	name = path.Clean("/" + name)
+	info, err := d.FileSystem.Stat(ctx, name)
+	switch {
+	case err == nil && d.hidden.hidden(name, info.IsDir()):
+		return &fs.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	case err != nil && d.hidden.hidden(name, false):
+		return &fs.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+	return nil
+}
+
+func (d *davFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if d.readOnly {
+		return os.ErrPermission
+	}
+	if err := d.check(ctx, name); err != nil {
+		return err
+	}
+	return d.FileSystem.Mkdir(ctx, name, perm)
+}
+
+func (d *davFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if d.readOnly && flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, os.ErrPermission
+	}
+	if err := d.check(ctx, name); err != nil {
+		return nil, err
+	}
+	f, err := d.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil || d.hidden == nil {
+		return f, err
+	}
+	return &davFile{File: f, visible: &hiddenFile{File: f, fs: d.hidden, name: path.Clean("/" + name)}}, nil
+}
+
+func (d *davFS) RemoveAll(ctx context.Context, name string) error {
+	if d.readOnly {
+		return os.ErrPermission
+	}
+	if err := d.check(ctx, name); err != nil {
+		return err
+	}
+	return d.FileSystem.RemoveAll(ctx, name)
+}
+
+func (d *davFS) Rename(ctx context.Context, oldName, newName string) error {
+	if d.readOnly {
+		return os.ErrPermission
+	}
+	if err := d.check(ctx, oldName); err != nil {
+		return err
+	}
+	if err := d.check(ctx, newName); err != nil {
+		return err
+	}
+	return d.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func (d *davFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := d.check(ctx, name); err != nil {
+		return nil, err
+	}
+	return d.FileSystem.Stat(ctx, name)
+}
+
+// davFile filters hidden entries out of directory listings.
+type davFile struct {
+	webdav.File
+	visible *hiddenFile
+}
+
+func (f *davFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return f.visible.Readdir(count)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func davRequest(t *testing.T, handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if method == "PROPFIND" {
+		req.Header.Set("Depth", "1")
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebDAVReadOnly(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"notes.txt":      "hello",
+		".env":           "SECRET=1",
+		".gitignore":     "build/\n",
+		"build/out.js":   "",
+		"docs/guide.txt": "",
+	})
+	handler := withWebDAV(okHandler, []Mount{{Prefix: "/", Dir: dir}}, WebDAVOptions{}, false)
+
+	rec := davRequest(t, handler, "PROPFIND", "/", "")
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<D:href>/notes.txt</D:href>")
+	assert.Contains(t, rec.Body.String(), "<D:href>/docs/</D:href>")
+	assert.NotContains(t, rec.Body.String(), ".env")
+	assert.NotContains(t, rec.Body.String(), "build")
+
+	assert.Equal(t, http.StatusNotFound, davRequest(t, handler, "PROPFIND", "/.env", "").Code)
+
+	rec = davRequest(t, handler, http.MethodOptions, "/", "")
+	assert.NotEmpty(t, rec.Header().Get("DAV"))
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete, "MKCOL", "MOVE", "LOCK"} {
+		rec := davRequest(t, handler, method, "/notes.txt", "changed")
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code, method)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	// Plain requests still go to the file server.
+	assert.Equal(t, "ok", davRequest(t, handler, http.MethodGet, "/notes.txt", "").Body.String())
+}
+
+func TestWebDAVReadWrite(t *testing.T) {
+	dir := writeTree(t, map[string]string{"notes.txt": "hello"})
+	handler := withWebDAV(okHandler, []Mount{{Prefix: "/files", Dir: dir}}, WebDAVOptions{Write: true}, false)
+
+	assert.Equal(t, http.StatusCreated, davRequest(t, handler, http.MethodPut, "/files/new.txt", "new").Code)
+	content, err := os.ReadFile(filepath.Join(dir, "new.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+
+	assert.Equal(t, http.StatusCreated, davRequest(t, handler, "MKCOL", "/files/drafts", "").Code)
+	assert.DirExists(t, filepath.Join(dir, "drafts"))
+
+	assert.Equal(t, http.StatusNoContent, davRequest(t, handler, http.MethodDelete, "/files/notes.txt", "").Code)
+	assert.NoFileExists(t, filepath.Join(dir, "notes.txt"))
+
+	// Hidden paths cannot be created.
+	assert.Equal(t, http.StatusNotFound, davRequest(t, handler, http.MethodPut, "/files/.env", "SECRET=1").Code)
+	assert.NoFileExists(t, filepath.Join(dir, ".env"))
+
+	rec := davRequest(t, handler, "PROPFIND", "/files/", "")
+	assert.Contains(t, rec.Body.String(), "<D:href>/files/new.txt</D:href>")
+
+	assert.Equal(t, http.StatusNotFound, davRequest(t, handler, "PROPFIND", "/elsewhere/", "").Code)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var protoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, r.Proto)
+})
+
+func TestTLSConfig(t *testing.T) {
+	tc, err := tlsConfig(defaultConfig())
+	require.NoError(t, err)
+	assert.Nil(t, tc)
+
+	cfg := defaultConfig()
+	cfg.TLSCert = "cert.pem"
+	_, err = tlsConfig(cfg)
+	assert.Error(t, err)
+
+	cfg = defaultConfig()
+	cfg.TLS = true
+	tc, err = tlsConfig(cfg)
+	require.NoError(t, err)
+	require.Len(t, tc.Certificates, 1)
+
+	cert, err := x509.ParseCertificate(tc.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	assert.NoError(t, cert.VerifyHostname("localhost"))
+	assert.NoError(t, cert.VerifyHostname("127.0.0.1"))
+}
+
+func TestServeHTTP2OverTLS(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.TLS = true
+	tc, err := tlsConfig(cfg)
+	require.NoError(t, err)
+
+	ln, err := listen("127.0.0.1", 0)
+	require.NoError(t, err)
+	srv := newServer(cfg, protoHandler)
+	srv.TLSConfig = tc
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "HTTP/2.0", string(body))
+}
+
+func TestServeH2C(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.H2C = true
+
+	ln, err := listen("127.0.0.1", 0)
+	require.NoError(t, err)
+	srv := newServer(cfg, protoHandler)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	resp, err := (&http.Client{Transport: transport}).Get("http://" + ln.Addr().String() + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "HTTP/2.0", string(body))
+
+	// Plain HTTP/1.1 clients still work.
+	resp, err = http.Get("http://" + ln.Addr().String() + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, "HTTP/1.1", string(body))
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// batch is a group of renames made by one run of slug, as recorded in the
+// journal.
+type batch struct {
+	ID      string         `json:"id"`
+	Time    time.Time      `json:"time"`
+	Dir     string         `json:"dir"`
+	Renames []journalEntry `json:"renames"`
+	// Undoes is the ID of the batch which this batch reverted.
+	Undoes string `json:"undoes,omitempty"`
+}
+
+// journalEntry is a single rename between absolute paths. The size and
+// modification time of files are kept to tell whether they changed since.
+type journalEntry struct {
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Dir     bool      `json:"dir,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"mod_time,omitzero"`
+}
+
+// journalFile returns the path of the journal in $XDG_STATE_HOME, which
+// defaults to ~/.local/state.
+func journalFile() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "slug", "history.jsonl"), nil
+}
+
+// newBatch describes renames which were just made.
+func newBatch(renames []rename) (batch, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return batch{}, err
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return batch{}, err
+	}
+
+	b := batch{ID: hex.EncodeToString(id), Time: time.Now(), Dir: dir, Renames: make([]journalEntry, len(renames))}
+	// Later renames of directories move earlier files, so entries are
+	// filled in latest first to know where to find them.
+	for i := len(renames) - 1; i >= 0; i-- {
+		e := &b.Renames[i]
+		if e.From, err = filepath.Abs(renames[i].From); err != nil {
+			return batch{}, err
+		}
+		if e.To, err = filepath.Abs(renames[i].To); err != nil {
+			return batch{}, err
+		}
+		info, err := os.Lstat(currentPath(b.Renames, i, e.To))
+		if err != nil {
+			return batch{}, err
+		}
+		e.describe(info)
+	}
+	return b, nil
+}
+
+// describe records what the renamed file looks like now.
+func (e *journalEntry) describe(info os.FileInfo) {
+	e.Dir = info.IsDir()
+	if !e.Dir {
+		e.Size = info.Size()
+		e.ModTime = info.ModTime()
+	}
+}
+
+// recordBatch appends the batch to the journal.
+func recordBatch(file string, b batch) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readJournal returns the batches in the journal, oldest first. A missing
+// journal has no batches.
+func readJournal(file string) ([]batch, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var batches []batch
+	dec := json.NewDecoder(f)
+	for {
+		var b batch
+		err := dec.Decode(&b)
+		if err == io.EOF {
+			return batches, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", file, err)
+		}
+		batches = append(batches, b)
+	}
+}
+
+// findBatch returns the batch with the ID, or the latest batch which has not
+// been undone if id is empty.
+func findBatch(batches []batch, id string) (batch, error) {
+	undone := map[string]bool{}
+	for _, b := range batches {
+		if b.Undoes != "" {
+			undone[b.Undoes] = true
+		}
+	}
+
+	for i := len(batches) - 1; i >= 0; i-- {
+		b := batches[i]
+		if id == "" && b.Undoes == "" && !undone[b.ID] {
+			return b, nil
+		}
+		if id != "" && b.ID == id {
+			if undone[b.ID] {
+				return batch{}, fmt.Errorf("batch %s has already been undone", id)
+			}
+			return b, nil
+		}
+	}
+	if id == "" {
+		return batch{}, errors.New("nothing to undo")
+	}
+	return batch{}, fmt.Errorf("no batch %s in the history", id)
+}
+
+// currentPath returns where path, as it was when entry i of the batch was
+// renamed, is now. Directories renamed later in the batch move it.
+func currentPath(renames []journalEntry, i int, path string) string {
+	for _, later := range renames[i+1:] {
+		if later.Dir && strings.HasPrefix(path, later.From+string(filepath.Separator)) {
+			path = later.To + path[len(later.From):]
+		}
+	}
+	return path
+}
+
+// checkUndo reports every reason the batch cannot be undone: files which
+// are missing or have changed since they were renamed, and files which have
+// taken their old names.
+func checkUndo(b batch) error {
+	var errs []error
+	for i, e := range b.Renames {
+		current := currentPath(b.Renames, i, e.To)
+		info, err := os.Lstat(current)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s is missing", current))
+			continue
+		}
+		if info.IsDir() != e.Dir || (!e.Dir && (info.Size() != e.Size || !info.ModTime().Equal(e.ModTime))) {
+			errs = append(errs, fmt.Errorf("%s has changed since it was renamed", current))
+		}
+		if original := currentPath(b.Renames, i, e.From); existsAsOtherFile(current, original) {
+			errs = append(errs, fmt.Errorf("%s exists again", original))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// undoBatch renames the files in the batch back, latest first, after
+// checking that they can all be renamed. It returns the batch of renames
+// which were made, to be recorded in the journal.
+func undoBatch(w io.Writer, b batch) (batch, error) {
+	if err := checkUndo(b); err != nil {
+		return batch{}, err
+	}
+
+	undo := batch{ID: b.ID + "-undo", Time: time.Now(), Dir: b.Dir, Undoes: b.ID}
+	if dir, err := os.Getwd(); err == nil {
+		undo.Dir = dir
+	}
+	var errs []error
+	for i := len(b.Renames) - 1; i >= 0; i-- {
+		e := b.Renames[i]
+		if err := os.Rename(e.To, e.From); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(w, "Renamed '%s' back to '%s'\n", filepath.Base(e.To), filepath.Base(e.From))
+		if info, err := os.Lstat(e.From); err == nil {
+			entry := journalEntry{From: e.To, To: e.From}
+			entry.describe(info)
+			undo.Renames = append(undo.Renames, entry)
+		}
+	}
+	return undo, errors.Join(errs...)
+}
+
+// runUndo implements slug undo, returning the exit status.
+func runUndo(args []string) int {
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	list := fs.Bool("l", false, "list the latest batches instead of undoing one")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s undo [-l] [batch ID]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	file, err := journalFile()
+	if err != nil {
+		fmt.Println("Error finding the history:", err)
+		return 1
+	}
+	batches, err := readJournal(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+
+	if *list {
+		printBatches(os.Stdout, batches, 10)
+		return 0
+	}
+
+	b, err := findBatch(batches, fs.Arg(0))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	undo, err := undoBatch(os.Stdout, b)
+	if len(undo.Renames) > 0 {
+		if err := recordBatch(file, undo); err != nil {
+			fmt.Println("Error recording the undo:", err)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error undoing batch %s:\n%v\n", b.ID, err)
+		return 1
+	}
+	return 0
+}
+
+// printBatches writes a table of the latest batches, newest first.
+func printBatches(w io.Writer, batches []batch, limit int) {
+	undone := map[string]bool{}
+	for _, b := range batches {
+		undone[b.Undoes] = true
+	}
+
+	t := table.NewWriter()
+	t.SetStyle(table.StyleRounded)
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"ID", "Time", "Directory", "Renames", "Note"})
+	for i := len(batches) - 1; i >= 0 && len(batches)-i <= limit; i-- {
+		b := batches[i]
+		var note string
+		switch {
+		case b.Undoes != "":
+			note = "undoes " + b.Undoes
+		case undone[b.ID]:
+			note = "undone"
+		}
+		t.AppendRow(table.Row{b.ID, b.Time.Local().Format(time.DateTime), b.Dir, strconv.Itoa(len(b.Renames)), note})
+	}
+	t.Render()
+}
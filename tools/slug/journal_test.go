@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renameTree renames everything under root, directories included, and
+// returns the batch recorded for it.
+func renameTree(t *testing.T, root string) batch {
+	t.Helper()
+	paths := expandGlobs([]string{filepath.Join(root, "*")}, walkOptions{Recursive: true, Dirs: true})
+	done := applyPlan(&bytes.Buffer{}, planRenames(paths, defaultSlugOptions(), collisionFail))
+	b, err := recordRenames(done)
+	require.NoError(t, err)
+	return b
+}
+
+func TestJournalFile(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+	file, err := journalFile()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/state", "slug", "history.jsonl"), file)
+
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	file, err = journalFile()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/home/me", ".local", "state", "slug", "history.jsonl"), file)
+}
+
+func TestRecordAndReadJournal(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	file, err := journalFile()
+	require.NoError(t, err)
+
+	batches, err := readJournal(file)
+	require.NoError(t, err)
+	assert.Empty(t, batches)
+
+	first := renameTree(t, makeTree(t, "A File.txt"))
+	second := renameTree(t, makeTree(t, "Sub Dir/B File.txt"))
+	assert.NotEqual(t, first.ID, second.ID)
+
+	batches, err = readJournal(file)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, first.ID, batches[0].ID)
+	require.Len(t, batches[1].Renames, 2)
+	assert.True(t, batches[1].Renames[1].Dir)
+	assert.True(t, filepath.IsAbs(batches[1].Renames[0].From))
+	assert.True(t, batches[1].Renames[0].ModTime.Equal(second.Renames[0].ModTime))
+}
+
+func TestFindBatch(t *testing.T) {
+	batches := []batch{{ID: "a"}, {ID: "b"}, {ID: "b-undo", Undoes: "b"}}
+
+	b, err := findBatch(batches, "")
+	require.NoError(t, err)
+	assert.Equal(t, "a", b.ID)
+
+	b, err = findBatch(batches, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", b.ID)
+
+	_, err = findBatch(batches, "b")
+	assert.ErrorContains(t, err, "already been undone")
+	_, err = findBatch(batches, "c")
+	assert.ErrorContains(t, err, "no batch c")
+	_, err = findBatch(batches[2:], "")
+	assert.ErrorContains(t, err, "nothing to undo")
+}
+
+func TestUndoBatch(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := makeTree(t, "Photos 2024/Summer Trip/IMG 1.jpg", "Photos 2024/IMG 2.jpg", "Notes.txt")
+	b := renameTree(t, root)
+	require.FileExists(t, filepath.Join(root, "photos-2024", "summer-trip", "img-1.jpg"))
+
+	var buf bytes.Buffer
+	undo, err := undoBatch(&buf, b)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "Renamed 'img-2.jpg' back to 'IMG 2.jpg'")
+	assert.Equal(t, b.ID, undo.Undoes)
+	assert.Len(t, undo.Renames, len(b.Renames))
+
+	assert.FileExists(t, filepath.Join(root, "Photos 2024", "Summer Trip", "IMG 1.jpg"))
+	assert.FileExists(t, filepath.Join(root, "Photos 2024", "IMG 2.jpg"))
+	assert.FileExists(t, filepath.Join(root, "Notes.txt"))
+	assert.NoDirExists(t, filepath.Join(root, "photos-2024"))
+}
+
+func TestUndoBatchChecksFiles(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := makeTree(t, "Sub Dir/A File.txt", "B File.txt", "C File.txt")
+	b := renameTree(t, root)
+
+	// One file is edited, one is deleted and one old name is taken again
+	changed := filepath.Join(root, "sub-dir", "a-file.txt")
+	require.NoError(t, os.WriteFile(changed, []byte("edited"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(root, "b-file.txt")))
+	createFiles(t, root, "C File.txt")
+
+	_, err := undoBatch(&bytes.Buffer{}, b)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, changed+" has changed")
+	assert.ErrorContains(t, err, filepath.Join(root, "b-file.txt")+" is missing")
+	assert.ErrorContains(t, err, filepath.Join(root, "C File.txt")+" exists again")
+
+	// Nothing was renamed back
+	assert.DirExists(t, filepath.Join(root, "sub-dir"))
+	assert.FileExists(t, filepath.Join(root, "c-file.txt"))
+}
+
+func TestUndoBatchChecksModTime(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := makeTree(t, "A File.txt")
+	b := renameTree(t, root)
+
+	touched := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "a-file.txt"), touched, touched))
+
+	_, err := undoBatch(&bytes.Buffer{}, b)
+	assert.ErrorContains(t, err, "has changed")
+}
+
+func TestPrintBatches(t *testing.T) {
+	batches := []batch{
+		{ID: "aaaa", Dir: "/photos", Renames: make([]journalEntry, 3)},
+		{ID: "bbbb", Dir: "/docs", Renames: make([]journalEntry, 1)},
+		{ID: "bbbb-undo", Dir: "/docs", Renames: make([]journalEntry, 1), Undoes: "bbbb"},
+	}
+
+	var buf bytes.Buffer
+	printBatches(&buf, batches, 2)
+	out := buf.String()
+	assert.Contains(t, out, "undoes bbbb")
+	assert.Contains(t, out, "undone")
+	assert.NotContains(t, out, "aaaa")
+}
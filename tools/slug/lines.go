@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// slugifyLines writes the slug of every line of r to w, one per line, so
+// that slug can filter text in scripts and editors. Blank lines stay blank.
+// Lines with nothing left after slugifying are written as blank lines and
+// reported in the error.
+func slugifyLines(r io.Reader, w io.Writer, slug slugOptions) error {
+	scanner := bufio.NewScanner(r)
+	var empty []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		s := slug.slugify(line)
+		if s == "" && strings.TrimSpace(line) != "" {
+			empty = append(empty, fmt.Sprintf("%q", line))
+		}
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(empty) > 0 {
+		return fmt.Errorf("nothing is left of %s", strings.Join(empty, ", "))
+	}
+	return nil
+}
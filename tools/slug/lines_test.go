@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugifyLines(t *testing.T) {
+	in := strings.NewReader("Some Title Here\n\nCafé Résumé\r\nfix: HTTPServer timeouts")
+
+	var out bytes.Buffer
+	require.NoError(t, slugifyLines(in, &out, defaultSlugOptions()))
+	assert.Equal(t, "some-title-here\n\ncafe-resume\nfix-http-server-timeouts\n", out.String())
+}
+
+func TestSlugifyLinesStyle(t *testing.T) {
+	slug := defaultSlugOptions()
+	slug.Style = styleTitle
+
+	var out bytes.Buffer
+	require.NoError(t, slugifyLines(strings.NewReader("weekly-review_2024"), &out, slug))
+	assert.Equal(t, "Weekly Review 2024\n", out.String())
+}
+
+func TestSlugifyLinesEmpty(t *testing.T) {
+	var out bytes.Buffer
+	err := slugifyLines(strings.NewReader("東京\nok\n!!!"), &out, defaultSlugOptions())
+	assert.ErrorContains(t, err, `nothing is left of "東京", "!!!"`)
+	assert.Equal(t, "\nok\n\n", out.String())
+}
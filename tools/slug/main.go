@@ -1,64 +1,150 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+	"golang.org/x/text/unicode/norm"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Printf("Usage: %s <file(s) or glob>\n", os.Args[0])
+	if len(os.Args) > 1 && os.Args[1] == "undo" {
+		os.Exit(runUndo(os.Args[2:]))
+	}
+
+	dryRun := flag.Bool("dry-run", false, "print the planned renames without renaming anything")
+	stringMode := flag.Bool("s", false, "print the slug of the arguments, or of each line of stdin if there are none, instead of renaming files")
+	onCollision := collisionFail
+	flag.Var(&onCollision, "on-collision", "what to do when a new name is taken: skip, suffix or fail")
+	var walk walkOptions
+	flag.BoolVar(&walk.Recursive, "r", false, "rename files in matched directories and their subdirectories")
+	flag.BoolVar(&walk.Dirs, "dirs", false, "rename directories too")
+	flag.BoolVar(&walk.FollowSymlinks, "follow-symlinks", false, "descend into symlinked directories with -r instead of skipping symlinks")
+	slug := defaultSlugOptions()
+	flag.Var(&slug.Style, "style", "case style: kebab, snake, camel, pascal, title, dot or screaming-snake")
+	flag.BoolVar(&slug.KeepUnicode, "keep-unicode", false, "keep letters of any script instead of transliterating to ASCII")
+	flag.Func("romanize", "comma-separated scripts to romanize: "+strings.Join(scripts, ", ")+" (default all)", func(value string) error {
+		var err error
+		slug.Romanize, err = parseScripts(value)
+		return err
+	})
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <file(s) or glob>\n       %s -s [flags] [string...]\n       %s undo [-l] [batch ID]\n", os.Args[0], os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *stringMode {
+		// The arguments make up one string, so that quoting is optional.
+		var in io.Reader = os.Stdin
+		if flag.NArg() > 0 {
+			in = strings.NewReader(strings.Join(flag.Args(), " "))
+		}
+		if err := slugifyLines(in, os.Stdout, slug); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() == 0 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	for _, arg := range os.Args[1:] {
-		matches, err := filepath.Glob(arg)
-		if err != nil {
-			fmt.Printf("Error processing glob '%s': %v\n", arg, err)
-			continue
+	if !isTerminal(os.Stdout) {
+		text.DisableColors()
+	}
+
+	plan := planRenames(expandGlobs(flag.Args(), walk), slug, onCollision)
+	if *dryRun {
+		printPlan(os.Stdout, plan)
+		if hasProblems(plan) {
+			os.Exit(1)
 		}
+		return
+	}
 
-		if len(matches) == 0 {
-			fmt.Printf("Error: '%s' is not a valid file or glob\n", arg)
-			continue
+	// Nothing is renamed unless the whole batch can be.
+	if hasProblems(plan) {
+		printPlan(os.Stdout, plan)
+		fmt.Println("Nothing was renamed.")
+		if hasCollisions(plan) {
+			fmt.Println("Use --on-collision skip or suffix to rename around collisions.")
 		}
+		os.Exit(1)
+	}
+	done := applyPlan(os.Stdout, plan)
+	if len(done) == 0 {
+		return
+	}
+	// The files have already been renamed, so failing to record them is
+	// only reported.
+	if b, err := recordRenames(done); err != nil {
+		fmt.Println("Error recording renames for undo:", err)
+	} else {
+		fmt.Printf("Undo with '%s undo %s'\n", filepath.Base(os.Args[0]), b.ID)
+	}
+}
+
+// recordRenames adds the renames to the journal as a batch which can be
+// undone.
+func recordRenames(renames []rename) (batch, error) {
+	file, err := journalFile()
+	if err != nil {
+		return batch{}, err
+	}
+	b, err := newBatch(renames)
+	if err != nil {
+		return batch{}, err
+	}
+	return b, recordBatch(file, b)
+}
 
-		for _, match := range matches {
-			fileInfo, err := os.Stat(match)
-			if err != nil {
-				fmt.Printf("Error getting info for '%s': %v\n", match, err)
-				continue
-			}
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
 
-			if !fileInfo.IsDir() {
-				renameFileToKebabCase(match)
-			}
-		}
+// slugOptions control how names are slugified.
+type slugOptions struct {
+	// Style is the case style of the slug.
+	Style style
+	// KeepUnicode keeps letters and digits of any script instead of
+	// transliterating them to ASCII.
+	KeepUnicode bool
+	// Romanize lists the scripts to romanize when transliterating.
+	Romanize []string
+}
+
+func defaultSlugOptions() slugOptions {
+	return slugOptions{Style: styleKebab, Romanize: scripts}
+}
+
+// slugify splits s into words and joins them in the style.
+func (o slugOptions) slugify(s string) string {
+	isWordRune := isASCIIAlphanumeric
+	if o.KeepUnicode {
+		s = norm.NFC.String(s)
+		isWordRune = isAlphanumeric
+	} else {
+		s = transliterate(s, o.Romanize)
 	}
+	return o.Style.join(splitWords(s, isWordRune))
 }
 
 func toKebabCase(s string) string {
-	// Convert string to lowercase
-	lowerStr := strings.ToLower(s)
-	// Replace non-alphanumeric characters with a dash
-	nonAlphanumeric := regexp.MustCompile(`[^a-zA-Z0-9]+`)
-	kebab := nonAlphanumeric.ReplaceAllString(lowerStr, "-")
-	// Trim dashes from the start and end
-	return strings.Trim(kebab, "-")
+	return defaultSlugOptions().slugify(s)
 }
 
+// renameFileToKebabCase renames a single file, leaving it alone if its new
+// name is taken.
 func renameFileToKebabCase(path string) {
-	directory, filename := filepath.Split(path)
-	extension := filepath.Ext(filename)
-	filenameNoExt := filename[0 : len(filename)-len(extension)]
-	kebabCaseFilename := toKebabCase(filenameNoExt) + extension
-	newPath := filepath.Join(directory, kebabCaseFilename)
-	if err := os.Rename(path, newPath); err != nil {
-		fmt.Printf("Error renaming '%s' to '%s': %v\n", filename, kebabCaseFilename, err)
-	} else {
-		fmt.Printf("Renamed '%s' to '%s'\n", filename, kebabCaseFilename)
-	}
+	applyPlan(os.Stdout, planRenames([]string{path}, defaultSlugOptions(), collisionSkip))
 }
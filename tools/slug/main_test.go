@@ -23,12 +23,12 @@ func TestToKebabCase(t *testing.T) {
 		{
 			name:     "camelCase",
 			input:    "helloWorld",
-			expected: "helloworld",
+			expected: "hello-world",
 		},
 		{
 			name:     "PascalCase",
 			input:    "HelloWorld",
-			expected: "helloworld",
+			expected: "hello-world",
 		},
 		{
 			name:     "snake_case",
@@ -53,7 +53,7 @@ func TestToKebabCase(t *testing.T) {
 		{
 			name:     "mixed case with numbers",
 			input:    "MyFile2024",
-			expected: "myfile2024",
+			expected: "my-file2024",
 		},
 		{
 			name:     "leading and trailing spaces",
@@ -85,10 +85,30 @@ func TestToKebabCase(t *testing.T) {
 			input:    "!@#$%^&*()",
 			expected: "",
 		},
+		{
+			name:     "acronyms",
+			input:    "HTTPServer",
+			expected: "http-server",
+		},
+		{
+			name:     "plural acronym",
+			input:    "IDs",
+			expected: "ids",
+		},
+		{
+			name:     "plural acronym in capitals",
+			input:    "URLs",
+			expected: "urls",
+		},
+		{
+			name:     "plural acronym before a word",
+			input:    "parseURLsFast",
+			expected: "parse-urls-fast",
+		},
 		{
 			name:     "unicode characters",
 			input:    "hello world café",
-			expected: "hello-world-caf",
+			expected: "hello-world-cafe",
 		},
 	}
 
@@ -116,7 +136,7 @@ func TestRenameFileToKebabCase(t *testing.T) {
 		{
 			name:         "camelCase",
 			originalFile: "myFileName.log",
-			expectedFile: "myfilename.log",
+			expectedFile: "my-file-name.log",
 		},
 		{
 			name:         "special characters",
@@ -316,3 +336,21 @@ func TestAlreadyKebabCase(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, content, newContent)
 }
+
+func TestRenameFileDoesNotOverwrite(t *testing.T) {
+	tempDir := t.TempDir()
+
+	existing := filepath.Join(tempDir, "my-file.txt")
+	require.NoError(t, os.WriteFile(existing, []byte("existing"), 0644))
+	originalFile := filepath.Join(tempDir, "My File.txt")
+	require.NoError(t, os.WriteFile(originalFile, []byte("original"), 0644))
+
+	renameFileToKebabCase(originalFile)
+
+	// Both files are left as they were
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "existing", string(content))
+	_, err = os.Stat(originalFile)
+	assert.NoError(t, err)
+}
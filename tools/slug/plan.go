@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// renameStatus describes what will happen to a file in a plan.
+type renameStatus int
+
+const (
+	statusRename renameStatus = iota
+	statusUnchanged
+	statusSkipped
+	statusEmpty
+	statusCollision
+)
+
+// collisionStrategy decides what happens to a file whose new name is taken,
+// either by an existing file or by an earlier file in the batch. Names with
+// nothing left after slugifying are skipped unless the strategy is fail.
+type collisionStrategy string
+
+const (
+	// collisionFail refuses to rename anything.
+	collisionFail collisionStrategy = "fail"
+	// collisionSkip leaves the file as it is.
+	collisionSkip collisionStrategy = "skip"
+	// collisionSuffix numbers the new name, as in my-file-2.txt.
+	collisionSuffix collisionStrategy = "suffix"
+)
+
+func (c *collisionStrategy) String() string { return string(*c) }
+
+func (c *collisionStrategy) Set(value string) error {
+	switch s := collisionStrategy(value); s {
+	case collisionFail, collisionSkip, collisionSuffix:
+		*c = s
+		return nil
+	}
+	return errors.New("must be one of skip, suffix or fail")
+}
+
+// isProblem reports whether the rename cannot safely go ahead.
+func (s renameStatus) isProblem() bool {
+	return s == statusEmpty || s == statusCollision
+}
+
+// rename is a single planned rename from one path to another.
+type rename struct {
+	From   string
+	To     string
+	Status renameStatus
+	// CollidesWith is the path of the other file that wants the same name
+	// or already has it. Skipped and suffixed renames keep it to explain
+	// themselves. Skipped renames without it have empty names.
+	CollidesWith string
+}
+
+// nameParts splits a file name into the leading dot of a hidden file, the
+// name to slugify and the extension to keep. The dot of .bashrc starts no
+// extension.
+func nameParts(filename string) (dot, name, extension string) {
+	if strings.HasPrefix(filename, ".") {
+		dot, filename = ".", filename[1:]
+	}
+	extension = filepath.Ext(filename)
+	return dot, filename[:len(filename)-len(extension)], extension
+}
+
+// planRenames works out the new name of every path without touching the
+// files. Paths which would end up with the same name, either as each other
+// or as a file which already exists, are resolved with the strategy. The
+// first file in the batch keeps the name. Paths with empty names are only
+// problems when the strategy is fail, and are skipped otherwise.
+func planRenames(paths []string, slug slugOptions, onCollision collisionStrategy) []rename {
+	plan := make([]rename, 0, len(paths))
+	for _, path := range paths {
+		directory, filename := filepath.Split(path)
+		dot, name, extension := nameParts(filename)
+		// Directories have no extensions to keep.
+		if isDir(path) {
+			name, extension = name+extension, ""
+		}
+		slugged := slug.slugify(name)
+		r := rename{From: path, To: filepath.Join(directory, dot+slugged+extension)}
+		switch {
+		case slugged == "" && onCollision == collisionFail:
+			r.Status = statusEmpty
+		case slugged == "":
+			r.Status = statusSkipped
+		case r.To == filepath.Clean(path):
+			r.Status = statusUnchanged
+		}
+		plan = append(plan, r)
+	}
+
+	// Files which keep their names claim them first, so that whatever is
+	// renamed onto them is the collision.
+	claimed := map[string]int{}
+	for i, r := range plan {
+		if r.Status == statusUnchanged {
+			claimed[r.To] = i
+		}
+	}
+	for i, r := range plan {
+		if r.Status != statusRename {
+			continue
+		}
+
+		j, taken := claimed[r.To]
+		switch {
+		case taken:
+			plan[i].CollidesWith = plan[j].From
+		case existsAsOtherFile(r.From, r.To):
+			plan[i].CollidesWith = r.To
+		default:
+			claimed[r.To] = i
+			continue
+		}
+
+		switch onCollision {
+		case collisionSkip:
+			plan[i].Status = statusSkipped
+		case collisionSuffix:
+			plan[i].To = freeName(r.From, r.To, claimed)
+			claimed[plan[i].To] = i
+		default:
+			plan[i].Status = statusCollision
+			if taken && plan[j].Status == statusRename {
+				plan[j].Status = statusCollision
+				plan[j].CollidesWith = r.From
+			}
+		}
+	}
+	return plan
+}
+
+// freeName numbers target, starting from 2, until it is neither claimed by
+// the batch nor an existing file.
+func freeName(path, target string, claimed map[string]int) string {
+	directory, filename := filepath.Split(target)
+	dot, name, extension := nameParts(filename)
+	for n := 2; ; n++ {
+		candidate := filepath.Join(directory, dot+name+"-"+strconv.Itoa(n)+extension)
+		if _, ok := claimed[candidate]; !ok && !existsAsOtherFile(path, candidate) {
+			return candidate
+		}
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// existsAsOtherFile reports whether target exists and is not the file at
+// path. Renaming README to readme on a case-insensitive file system finds
+// the file itself, which is not a collision.
+func existsAsOtherFile(path, target string) bool {
+	targetInfo, err := os.Lstat(target)
+	if err != nil {
+		return false
+	}
+	info, err := os.Lstat(path)
+	return err != nil || !os.SameFile(info, targetInfo)
+}
+
+// hasProblems reports whether any rename in the plan cannot go ahead.
+func hasProblems(plan []rename) bool {
+	for _, r := range plan {
+		if r.Status.isProblem() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCollisions reports whether any rename in the plan collides with
+// another, as opposed to having an empty name.
+func hasCollisions(plan []rename) bool {
+	for _, r := range plan {
+		if r.Status == statusCollision {
+			return true
+		}
+	}
+	return false
+}
+
+// printPlan writes the plan as a table of old and new names, followed by a
+// summary. Unchanged names and problems are highlighted.
+func printPlan(w io.Writer, plan []rename) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleRounded)
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"Old", "New", "Note"})
+
+	var renames, unchanged, skipped, problems int
+	for _, r := range plan {
+		var note string
+		var colors text.Colors
+		switch r.Status {
+		case statusRename:
+			renames++
+			if r.CollidesWith != "" {
+				note = "suffixed, " + r.CollidesWith + " has the name"
+				colors = text.Colors{text.FgYellow}
+			}
+		case statusUnchanged:
+			unchanged++
+			note = "unchanged"
+			colors = text.Colors{text.FgYellow}
+		case statusSkipped:
+			skipped++
+			note = "skipped, " + r.CollidesWith + " has the name"
+			if r.CollidesWith == "" {
+				note = "skipped, empty name"
+			}
+			colors = text.Colors{text.FgYellow}
+		case statusEmpty:
+			problems++
+			note = "empty name"
+			colors = text.Colors{text.FgRed}
+		case statusCollision:
+			problems++
+			note = "collides with " + r.CollidesWith
+			colors = text.Colors{text.FgRed}
+		}
+		t.AppendRow(table.Row{colors.Sprint(r.From), colors.Sprint(r.To), colors.Sprint(note)})
+	}
+
+	t.Render()
+	fmt.Fprintf(w, "%d to rename, %d unchanged, %d skipped, %d %s\n", renames, unchanged, skipped, problems, plural(problems, "problem"))
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// applyPlan renames the files in the plan, printing what it does, and
+// returns the renames which were made. A file which appeared at a new name
+// since the plan was made is never overwritten.
+func applyPlan(w io.Writer, plan []rename) []rename {
+	var done []rename
+	for _, r := range plan {
+		oldName, newName := filepath.Base(r.From), filepath.Base(r.To)
+		switch r.Status {
+		case statusRename:
+		case statusSkipped:
+			if r.CollidesWith == "" {
+				fmt.Fprintf(w, "Skipped '%s': nothing is left of the name\n", oldName)
+			} else {
+				fmt.Fprintf(w, "Skipped '%s': '%s' has the name '%s'\n", oldName, filepath.Base(r.CollidesWith), newName)
+			}
+			continue
+		case statusEmpty:
+			fmt.Fprintf(w, "Error renaming '%s': nothing is left of the name\n", oldName)
+			continue
+		case statusCollision:
+			fmt.Fprintf(w, "Error renaming '%s' to '%s': '%s' has the same name\n", oldName, newName, filepath.Base(r.CollidesWith))
+			continue
+		default:
+			continue
+		}
+
+		if existsAsOtherFile(r.From, r.To) {
+			fmt.Fprintf(w, "Error renaming '%s' to '%s': file exists\n", oldName, newName)
+			continue
+		}
+		if err := os.Rename(r.From, r.To); err != nil {
+			fmt.Fprintf(w, "Error renaming '%s' to '%s': %v\n", oldName, newName, err)
+		} else {
+			fmt.Fprintf(w, "Renamed '%s' to '%s'\n", oldName, newName)
+			done = append(done, r)
+		}
+	}
+	return done
+}
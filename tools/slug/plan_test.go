@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createFiles creates empty files with the given names in dir and returns
+// their paths.
+func createFiles(t *testing.T, dir string, names ...string) []string {
+	t.Helper()
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestNameParts(t *testing.T) {
+	tests := []struct {
+		filename, dot, name, extension string
+	}{
+		{"My File.txt", "", "My File", ".txt"},
+		{"file.tar.gz", "", "file.tar", ".gz"},
+		{"README", "", "README", ""},
+		{".bashrc", ".", "bashrc", ""},
+		{".env.local", ".", "env", ".local"},
+	}
+	for _, tt := range tests {
+		dot, name, extension := nameParts(tt.filename)
+		assert.Equal(t, tt.dot, dot, tt.filename)
+		assert.Equal(t, tt.name, name, tt.filename)
+		assert.Equal(t, tt.extension, extension, tt.filename)
+	}
+}
+
+func TestPlanRenames(t *testing.T) {
+	dir := t.TempDir()
+	paths := createFiles(t, dir, "My File.txt", "already-kebab.txt", "!!!.txt", ".bashrc", ".Env Local.txt", "Café.TXT")
+
+	plan := planRenames(paths, defaultSlugOptions(), collisionFail)
+	require.Len(t, plan, 6)
+
+	assert.Equal(t, filepath.Join(dir, "my-file.txt"), plan[0].To)
+	assert.Equal(t, statusRename, plan[0].Status)
+	assert.Equal(t, statusUnchanged, plan[1].Status)
+	assert.Equal(t, statusEmpty, plan[2].Status)
+	assert.Equal(t, statusUnchanged, plan[3].Status)
+	assert.Equal(t, filepath.Join(dir, ".env-local.txt"), plan[4].To)
+	assert.Equal(t, filepath.Join(dir, "cafe.TXT"), plan[5].To)
+	assert.True(t, hasProblems(plan))
+	assert.False(t, hasCollisions(plan))
+	assert.False(t, hasProblems(plan[:2]))
+	assert.False(t, hasProblems(plan[3:]))
+
+	// Planning never touches the files
+	for _, path := range paths {
+		assert.FileExists(t, path)
+	}
+}
+
+func TestPlanRenamesCollisionsInBatch(t *testing.T) {
+	dir := t.TempDir()
+	paths := createFiles(t, dir, "My File.txt", "my file.txt", "Other.txt")
+
+	plan := planRenames(paths, defaultSlugOptions(), collisionFail)
+	assert.Equal(t, statusCollision, plan[0].Status)
+	assert.Equal(t, paths[1], plan[0].CollidesWith)
+	assert.Equal(t, statusCollision, plan[1].Status)
+	assert.Equal(t, paths[0], plan[1].CollidesWith)
+	assert.Equal(t, statusRename, plan[2].Status)
+	assert.True(t, hasCollisions(plan))
+}
+
+func TestPlanRenamesCollisionsWithUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	paths := createFiles(t, dir, "My File.txt", "my-file.txt")
+
+	plan := planRenames(paths, defaultSlugOptions(), collisionFail)
+	assert.Equal(t, statusCollision, plan[0].Status)
+	assert.Equal(t, paths[1], plan[0].CollidesWith)
+	assert.Equal(t, statusUnchanged, plan[1].Status)
+}
+
+func TestPlanRenamesCollisionsWithExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	createFiles(t, dir, "my-file.txt")
+	paths := createFiles(t, dir, "My File.txt")
+
+	plan := planRenames(paths, defaultSlugOptions(), collisionFail)
+	assert.Equal(t, statusCollision, plan[0].Status)
+	assert.Equal(t, filepath.Join(dir, "my-file.txt"), plan[0].CollidesWith)
+}
+
+func TestPrintPlan(t *testing.T) {
+	plan := []rename{
+		{From: "My File.txt", To: "my-file.txt"},
+		{From: "done.txt", To: "done.txt", Status: statusUnchanged},
+		{From: "!!!.txt", To: ".txt", Status: statusEmpty},
+		{From: "A.txt", To: "a.txt", Status: statusCollision, CollidesWith: "a.txt"},
+		{From: "???.md", To: ".md", Status: statusSkipped},
+	}
+
+	var buf bytes.Buffer
+	printPlan(&buf, plan)
+	out := buf.String()
+
+	assert.Contains(t, out, "My File.txt")
+	assert.Contains(t, out, "my-file.txt")
+	assert.Contains(t, out, "unchanged")
+	assert.Contains(t, out, "empty name")
+	assert.Contains(t, out, "collides with a.txt")
+	assert.Contains(t, out, "skipped, empty name")
+	assert.Contains(t, out, "1 to rename, 1 unchanged, 1 skipped, 2 problems")
+}
+
+func TestPlanRenamesSkipCollisions(t *testing.T) {
+	dir := t.TempDir()
+	createFiles(t, dir, "my-file.txt")
+	paths := createFiles(t, dir, "My File.txt", "Other.txt", "!!!.txt")
+
+	plan := planRenames(paths, defaultSlugOptions(), collisionSkip)
+	assert.Equal(t, statusSkipped, plan[0].Status)
+	assert.Equal(t, filepath.Join(dir, "my-file.txt"), plan[0].CollidesWith)
+	assert.Equal(t, statusRename, plan[1].Status)
+	assert.Equal(t, statusSkipped, plan[2].Status)
+	assert.Empty(t, plan[2].CollidesWith)
+	assert.False(t, hasProblems(plan))
+
+	var buf bytes.Buffer
+	applyPlan(&buf, plan)
+	assert.Contains(t, buf.String(), "Skipped '!!!.txt': nothing is left of the name")
+	assert.FileExists(t, paths[2])
+}
+
+func TestPlanRenamesSuffixCollisions(t *testing.T) {
+	dir := t.TempDir()
+	createFiles(t, dir, "my-file-2.txt")
+	paths := createFiles(t, dir, "My File.txt", "my file.txt", "MY FILE.txt", "!!!.txt")
+
+	plan := planRenames(paths, defaultSlugOptions(), collisionSuffix)
+	assert.Equal(t, filepath.Join(dir, "my-file.txt"), plan[0].To)
+	assert.Empty(t, plan[0].CollidesWith)
+	assert.Equal(t, filepath.Join(dir, "my-file-3.txt"), plan[1].To)
+	assert.Equal(t, paths[0], plan[1].CollidesWith)
+	assert.Equal(t, filepath.Join(dir, "my-file-4.txt"), plan[2].To)
+	assert.Equal(t, statusSkipped, plan[3].Status)
+	assert.False(t, hasProblems(plan))
+}
+
+func TestCollisionStrategySet(t *testing.T) {
+	var c collisionStrategy
+	require.NoError(t, c.Set("suffix"))
+	assert.Equal(t, collisionSuffix, c)
+	assert.Error(t, c.Set("overwrite"))
+	assert.Equal(t, collisionSuffix, c)
+}
+
+func TestApplyPlan(t *testing.T) {
+	dir := t.TempDir()
+	paths := createFiles(t, dir, "My File.txt", "my file.txt")
+
+	var buf bytes.Buffer
+	applyPlan(&buf, planRenames(paths, defaultSlugOptions(), collisionSuffix))
+	assert.Contains(t, buf.String(), "Renamed 'My File.txt' to 'my-file.txt'")
+	assert.Contains(t, buf.String(), "Renamed 'my file.txt' to 'my-file-2.txt'")
+	assert.FileExists(t, filepath.Join(dir, "my-file.txt"))
+	assert.FileExists(t, filepath.Join(dir, "my-file-2.txt"))
+}
+
+func TestApplyPlanNeverOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	paths := createFiles(t, dir, "My File.txt")
+	plan := planRenames(paths, defaultSlugOptions(), collisionFail)
+
+	// Another program creates the file after the plan was made
+	target := filepath.Join(dir, "my-file.txt")
+	require.NoError(t, os.WriteFile(target, []byte("keep me"), 0644))
+
+	var buf bytes.Buffer
+	applyPlan(&buf, plan)
+	assert.Contains(t, buf.String(), "file exists")
+	assert.FileExists(t, paths[0])
+	content, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "keep me", string(content))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// style is a case style that words are joined in.
+type style string
+
+const (
+	styleKebab          style = "kebab"
+	styleSnake          style = "snake"
+	styleCamel          style = "camel"
+	stylePascal         style = "pascal"
+	styleTitle          style = "title"
+	styleDot            style = "dot"
+	styleScreamingSnake style = "screaming-snake"
+)
+
+// styles lists every style in the order they are listed in help.
+var styles = []style{styleKebab, styleSnake, styleCamel, stylePascal, styleTitle, styleDot, styleScreamingSnake}
+
+func (s *style) String() string { return string(*s) }
+
+func (s *style) Set(value string) error {
+	for _, known := range styles {
+		if style(value) == known {
+			*s = known
+			return nil
+		}
+	}
+	names := make([]string, len(styles))
+	for i, known := range styles {
+		names[i] = string(known)
+	}
+	return errors.New("must be one of " + strings.Join(names, ", "))
+}
+
+// join joins words in the style.
+func (s style) join(words []string) string {
+	styled := make([]string, len(words))
+	for i, word := range words {
+		switch {
+		case s == styleScreamingSnake:
+			styled[i] = strings.ToUpper(word)
+		case s == stylePascal || s == styleTitle || (s == styleCamel && i > 0):
+			styled[i] = capitalize(word)
+		default:
+			styled[i] = strings.ToLower(word)
+		}
+	}
+
+	switch s {
+	case styleSnake, styleScreamingSnake:
+		return strings.Join(styled, "_")
+	case styleCamel, stylePascal:
+		return strings.Join(styled, "")
+	case styleTitle:
+		return strings.Join(styled, " ")
+	case styleDot:
+		return strings.Join(styled, ".")
+	}
+	return strings.Join(styled, "-")
+}
+
+// capitalize uppercases the first letter of word and lowercases the rest.
+func capitalize(word string) string {
+	first, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToTitle(first)) + strings.ToLower(word[size:])
+}
+
+// splitWords splits s into words. Runes for which isWordRune returns false
+// separate words, and so do changes of case: "HTTPServer" is split into
+// "HTTP" and "Server", and "myFile2024" into "my" and "File2024". Digits
+// stay with the letters before them, and so does the s of plural acronyms
+// such as "URLs".
+func splitWords(s string, isWordRune func(rune) bool) []string {
+	var words []string
+	runes := []rune(s)
+	start := -1
+	for i, r := range runes {
+		if !isWordRune(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+
+		prev := runes[i-1]
+		lowerToUpper := (unicode.IsLower(prev) || unicode.IsDigit(prev)) && unicode.IsUpper(r)
+		acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !isPluralS(runes, i+1, isWordRune)
+		if lowerToUpper || acronymEnd {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// isPluralS reports whether the rune at i is an s which ends a word, as in
+// "IDs" or "URLsFast".
+func isPluralS(runes []rune, i int, isWordRune func(rune) bool) bool {
+	if runes[i] != 's' {
+		return false
+	}
+	return i+1 == len(runes) || !isWordRune(runes[i+1]) || unicode.IsUpper(runes[i+1])
+}
+
+// isASCIIAlphanumeric reports whether r is an ASCII letter or digit.
+func isASCIIAlphanumeric(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// isAlphanumeric reports whether r is a letter, mark or digit of any script.
+func isAlphanumeric(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsNumber(r)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"hello world", []string{"hello", "world"}},
+		{"helloWorld", []string{"hello", "World"}},
+		{"HelloWorld", []string{"Hello", "World"}},
+		{"HTTPServer", []string{"HTTP", "Server"}},
+		{"parseHTTPResponse", []string{"parse", "HTTP", "Response"}},
+		{"HTTP2Server", []string{"HTTP2", "Server"}},
+		{"IDs", []string{"IDs"}},
+		{"userAPIs", []string{"user", "APIs"}},
+		{"parseURLsFast", []string{"parse", "URLs", "Fast"}},
+		{"URLs.txt", []string{"URLs", "txt"}},
+		{"file123name456", []string{"file123name456"}},
+		{"MyFile2024", []string{"My", "File2024"}},
+		{"ALL CAPS", []string{"ALL", "CAPS"}},
+		{"snake_case-and.dots", []string{"snake", "case", "and", "dots"}},
+		{"  --  ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitWords(tt.input, isASCIIAlphanumeric))
+		})
+	}
+}
+
+func TestSplitWordsUnicode(t *testing.T) {
+	assert.Equal(t, []string{"Ёлка", "Новая"}, splitWords("ЁлкаНовая", isAlphanumeric))
+	assert.Equal(t, []string{"東京", "2024"}, splitWords("東京 2024", isAlphanumeric))
+}
+
+func TestStyleJoin(t *testing.T) {
+	words := splitWords("HTTPServer config v2", isASCIIAlphanumeric)
+
+	tests := []struct {
+		style    style
+		expected string
+	}{
+		{styleKebab, "http-server-config-v2"},
+		{styleSnake, "http_server_config_v2"},
+		{styleCamel, "httpServerConfigV2"},
+		{stylePascal, "HttpServerConfigV2"},
+		{styleTitle, "Http Server Config V2"},
+		{styleDot, "http.server.config.v2"},
+		{styleScreamingSnake, "HTTP_SERVER_CONFIG_V2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.style.join(words))
+		})
+	}
+}
+
+func TestStyleSet(t *testing.T) {
+	var s style
+	require.NoError(t, s.Set("screaming-snake"))
+	assert.Equal(t, styleScreamingSnake, s)
+	assert.Error(t, s.Set("sponge"))
+	assert.Equal(t, styleScreamingSnake, s)
+}
+
+func TestSlugifyStyles(t *testing.T) {
+	slug := defaultSlugOptions()
+	slug.Style = stylePascal
+	assert.Equal(t, "UserProfileCard", slug.slugify("user profile card"))
+	assert.Equal(t, "CafeMenu", slug.slugify("café menu"))
+
+	slug.Style = styleSnake
+	assert.Equal(t, "data_loader", slug.slugify("DataLoader"))
+}
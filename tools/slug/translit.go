@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// scripts are the scripts which can be romanized, in the order they are
+// listed in help.
+var scripts = []string{"cyrillic", "greek", "kana"}
+
+// letters maps characters that decomposition leaves alone to their usual
+// spelling in ASCII.
+var letters = map[rune]string{
+	'ß': "ss", 'ẞ': "SS",
+	'æ': "ae", 'Æ': "AE",
+	'œ': "oe", 'Œ': "OE",
+	'ø': "o", 'Ø': "O",
+	'đ': "d", 'Đ': "D",
+	'ð': "d", 'Ð': "D",
+	'þ': "th", 'Þ': "TH",
+	'ł': "l", 'Ł': "L",
+	'ħ': "h", 'Ħ': "H",
+	'ı': "i",
+}
+
+// romanizations maps the lowercase letters of each script to Latin.
+var romanizations = map[string]map[rune]string{
+	"cyrillic": {
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d",
+		'е': "e", 'ё': "yo", 'є': "ye", 'ж': "zh", 'з': "z", 'и': "i",
+		'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+		'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+		'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+		'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+		'я': "ya",
+	},
+	"greek": {
+		'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z",
+		'η': "i", 'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m",
+		'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+		'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+		'ω': "o",
+	},
+	// Hepburn romanization of hiragana. Katakana is converted to hiragana
+	// first.
+	"kana": {
+		'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+		'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+		'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+		'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+		'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+		'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+		'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+		'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+		'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+		'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+		'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+		'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+		'や': "ya", 'ゆ': "yu", 'よ': "yo",
+		'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+		'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n",
+		'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+		'ゔ': "vu", 'ー': "",
+	},
+}
+
+// parseScripts parses a comma-separated list of scripts to romanize.
+func parseScripts(value string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := romanizations[name]; !ok {
+			return nil, errors.New("scripts must be among " + strings.Join(scripts, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// transliterate spells s in ASCII as far as it can. Diacritics are stripped,
+// ligatures and letters such as ß are spelled out, and the given scripts are
+// romanized. Anything else, such as kanji, is left for the caller to drop.
+func transliterate(s string, romanize []string) string {
+	var table map[rune]string
+	if len(romanize) > 0 {
+		table = map[rune]string{}
+		for _, name := range romanize {
+			for r, latin := range romanizations[name] {
+				table[r] = latin
+			}
+		}
+	}
+
+	runes := []rune(norm.NFC.String(s))
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if latin, ok := letters[r]; ok {
+			b.WriteString(matchCase(latin, runes, i))
+			continue
+		}
+		if latin, ok := romanizeRune(table, r); ok {
+			latin = matchCase(latin, runes, i)
+			// Small ya, yu and yo combine with the kana before them, as in
+			// kya and sha.
+			if i+1 < len(runes) {
+				if vowel, ok := smallY[hiragana(runes[i+1])]; ok && strings.HasSuffix(latin, "i") {
+					latin = strings.TrimSuffix(latin, "i")
+					if !strings.HasSuffix(latin, "sh") && !strings.HasSuffix(latin, "ch") && !strings.HasSuffix(latin, "j") {
+						latin += "y"
+					}
+					latin += vowel
+					i++
+				}
+			}
+			b.WriteString(latin)
+			continue
+		}
+		if hiragana(r) == 'っ' && table != nil {
+			// A small tsu doubles the consonant after it.
+			if i+1 < len(runes) {
+				if next, ok := romanizeRune(table, runes[i+1]); ok && next != "" {
+					if strings.HasPrefix(next, "ch") {
+						b.WriteString("t")
+					} else {
+						b.WriteString(next[:1])
+					}
+				}
+			}
+			continue
+		}
+
+		// Decompose, keeping the base letters and dropping the marks.
+		for _, d := range norm.NFKD.String(string(r)) {
+			if unicode.Is(unicode.Mn, d) {
+				continue
+			}
+			if latin, ok := romanizeRune(table, d); ok {
+				b.WriteString(matchCase(latin, runes, i))
+			} else {
+				b.WriteRune(d)
+			}
+		}
+	}
+	return b.String()
+}
+
+// smallY maps the small kana which form digraphs to their vowels.
+var smallY = map[rune]string{'ゃ': "a", 'ゅ': "u", 'ょ': "o"}
+
+// romanizeRune looks r up in the table, in lowercase.
+func romanizeRune(table map[rune]string, r rune) (string, bool) {
+	if table == nil {
+		return "", false
+	}
+	latin, ok := table[hiragana(unicode.ToLower(r))]
+	return latin, ok
+}
+
+// matchCase cases the spelling of the letter at i like the letter. Among
+// other capitals, as in ШКОЛА, it is all in capitals, and otherwise only its
+// first letter is, as in Школа. Either way the word stays in one piece when
+// it is split at changes of case.
+func matchCase(latin string, runes []rune, i int) string {
+	if !unicode.IsUpper(runes[i]) || latin == "" {
+		return latin
+	}
+	if (i > 0 && unicode.IsUpper(runes[i-1])) || (i+1 < len(runes) && unicode.IsUpper(runes[i+1])) {
+		return strings.ToUpper(latin)
+	}
+	return strings.ToUpper(latin[:1]) + strings.ToLower(latin[1:])
+}
+
+// hiragana converts katakana to the matching hiragana.
+func hiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - 'ァ' + 'ぁ'
+	}
+	return r
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"diacritics", "Café Résumé", "Cafe Resume"},
+		{"decomposed diacritics", "Cafe\u0301", "Cafe"},
+		{"sharp s", "Straße", "Strasse"},
+		{"ligatures", "Æsop œuvre ﬁle", "Aesop oeuvre file"},
+		{"ligatures in capitals", "ÆSOP", "AESOP"},
+		{"nordic", "Ørsted Þór", "Orsted Thor"},
+		{"polish", "Łódź", "Lodz"},
+		{"full width", "ＡＢＣ１２３", "ABC123"},
+		{"cyrillic", "Привет мир", "Privet mir"},
+		{"cyrillic short i", "Чайка", "Chayka"},
+		{"cyrillic capitals", "ШКОЛА ЖУК", "SHKOLA ZHUK"},
+		{"cyrillic capital letter", "Ж", "Zh"},
+		{"greek", "Αθήνα", "Athina"},
+		{"greek capitals", "ΑΘΗΝΑ ΑΘΉΝΑ", "ATHINA ATHINA"},
+		{"hiragana", "さくら", "sakura"},
+		{"katakana", "カメラ", "kamera"},
+		{"voiced kana", "ゲーム", "gemu"},
+		{"digraphs", "きょうと しゃしん", "kyouto shashin"},
+		{"small tsu", "きって マッチ", "kitte matchi"},
+		{"kanji are left alone", "東京", "東京"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, transliterate(tt.input, scripts))
+		})
+	}
+}
+
+func TestTransliterateWithoutRomanizing(t *testing.T) {
+	assert.Equal(t, "Привет Cafe", transliterate("Привет Café", nil))
+	assert.Equal(t, "Привет", transliterate("Привет", []string{"greek"}))
+}
+
+func TestParseScripts(t *testing.T) {
+	names, err := parseScripts("Greek, kana")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"greek", "kana"}, names)
+
+	names, err = parseScripts("")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	_, err = parseScripts("klingon")
+	assert.Error(t, err)
+}
+
+func TestSlugify(t *testing.T) {
+	slug := defaultSlugOptions()
+	assert.Equal(t, "cafe-resume", slug.slugify("Café Résumé"))
+	assert.Equal(t, "strasse", slug.slugify("Straße"))
+	assert.Equal(t, "sakura-no-hana", slug.slugify("さくら の はな"))
+	assert.Equal(t, "", slug.slugify("東京"))
+	assert.Equal(t, "shkola", slug.slugify("ШКОЛА"))
+	assert.Equal(t, "athina", slug.slugify("ΑΘΗΝΑ"))
+	assert.Equal(t, "zhuk", slug.slugify("ЖУК"))
+	assert.Equal(t, "thor-aesop", slug.slugify("Þór Æsop"))
+
+	pascal := slug
+	pascal.Style = stylePascal
+	assert.Equal(t, "Zhuk", pascal.slugify("ЖУК"))
+	assert.Equal(t, "ShkolaZhuk", pascal.slugify("школа ЖУК"))
+
+	slug.KeepUnicode = true
+	assert.Equal(t, "café-résumé", slug.slugify("Café  Résumé!"))
+	assert.Equal(t, "café", slug.slugify("Cafe\u0301"))
+	assert.Equal(t, "東京-2024", slug.slugify("東京 (2024)"))
+	assert.Equal(t, "привет-мир", slug.slugify("Привет, мир"))
+}
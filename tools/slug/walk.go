@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// walkOptions control which paths the globs expand to.
+type walkOptions struct {
+	// Recursive descends into matched directories.
+	Recursive bool
+	// Dirs renames directories as well as files.
+	Dirs bool
+	// FollowSymlinks descends into symlinked directories found while
+	// walking. Otherwise symlinks inside a tree are left alone.
+	FollowSymlinks bool
+}
+
+// expandGlobs returns the paths matching the globs. Directories are only
+// included, or walked, as the options allow. Within a tree, children come
+// before their directory so that renaming a directory never moves a path
+// which is still to be renamed. Errors are printed and the offending path is
+// skipped.
+func expandGlobs(globs []string, walk walkOptions) []string {
+	w := walker{walkOptions: walk, seen: map[string]bool{}, visited: map[string]bool{}}
+	for _, arg := range globs {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			fmt.Printf("Error processing glob '%s': %v\n", arg, err)
+			continue
+		}
+
+		if len(matches) == 0 {
+			fmt.Printf("Error: '%s' is not a valid file or glob\n", arg)
+			continue
+		}
+
+		for _, match := range matches {
+			fileInfo, err := os.Stat(match)
+			if err != nil {
+				fmt.Printf("Error getting info for '%s': %v\n", match, err)
+				continue
+			}
+
+			if !fileInfo.IsDir() {
+				w.add(match)
+				continue
+			}
+			if walk.Recursive {
+				w.walk(match)
+			}
+			if walk.Dirs && !isDotName(filepath.Base(match)) {
+				w.add(match)
+			}
+		}
+	}
+	return w.paths
+}
+
+// walker collects the paths in directory trees.
+type walker struct {
+	walkOptions
+	paths []string
+	seen  map[string]bool
+	// visited holds the real paths of walked directories, so that symlinks
+	// cannot lead around in circles.
+	visited map[string]bool
+}
+
+func (w *walker) add(path string) {
+	if !w.seen[path] {
+		w.seen[path] = true
+		w.paths = append(w.paths, path)
+	}
+}
+
+// walk adds the contents of dir, depth-first. Hidden files and directories,
+// such as .git, are never touched.
+func (w *walker) walk(dir string) {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		fmt.Printf("Error reading '%s': %v\n", dir, err)
+		return
+	}
+	if w.visited[resolved] {
+		return
+	}
+	w.visited[resolved] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Printf("Error reading '%s': %v\n", dir, err)
+		return
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if !w.FollowSymlinks {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				fmt.Printf("Error getting info for '%s': %v\n", path, err)
+				continue
+			}
+			isDir = info.IsDir()
+		}
+
+		if !isDir {
+			w.add(path)
+			continue
+		}
+		w.walk(path)
+		if w.Dirs {
+			w.add(path)
+		}
+	}
+}
+
+// isDotName reports whether name is . or .., which cannot be renamed.
+func isDotName(name string) bool {
+	return name == "." || name == ".." || name == string(filepath.Separator)
+}
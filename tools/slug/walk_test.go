@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeTree creates a directory tree in a new temporary directory. Names
+// ending in a slash are directories, the rest are empty files.
+func makeTree(t *testing.T, names ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range names {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if name[len(name)-1] == '/' {
+			require.NoError(t, os.MkdirAll(path, 0755))
+			continue
+		}
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+	return root
+}
+
+// relativePaths returns paths relative to root, with slashes.
+func relativePaths(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+	rel := make([]string, len(paths))
+	for i, path := range paths {
+		r, err := filepath.Rel(root, path)
+		require.NoError(t, err)
+		rel[i] = filepath.ToSlash(r)
+	}
+	return rel
+}
+
+func TestExpandGlobs(t *testing.T) {
+	root := makeTree(t, "A File.txt", "B File.txt", "Sub Dir/C File.txt")
+
+	paths := expandGlobs([]string{filepath.Join(root, "*"), filepath.Join(root, "A*")}, walkOptions{})
+	assert.Equal(t, []string{"A File.txt", "B File.txt"}, relativePaths(t, root, paths))
+
+	paths = expandGlobs([]string{filepath.Join(root, "*")}, walkOptions{Dirs: true})
+	assert.Equal(t, []string{"A File.txt", "B File.txt", "Sub Dir"}, relativePaths(t, root, paths))
+}
+
+func TestExpandGlobsRecursive(t *testing.T) {
+	root := makeTree(t,
+		"Top File.txt",
+		"Photos 2024/IMG 1.jpg",
+		"Photos 2024/Summer Trip/IMG 2.jpg",
+		"Photos 2024/.git/HEAD",
+		"Photos 2024/.DS_Store",
+		"Empty Dir/",
+	)
+
+	paths := expandGlobs([]string{root}, walkOptions{Recursive: true})
+	assert.Equal(t, []string{
+		"Photos 2024/IMG 1.jpg",
+		"Photos 2024/Summer Trip/IMG 2.jpg",
+		"Top File.txt",
+	}, relativePaths(t, root, paths))
+
+	// Directories come after everything inside them
+	paths = expandGlobs([]string{filepath.Join(root, "*")}, walkOptions{Recursive: true, Dirs: true})
+	assert.Equal(t, []string{
+		"Empty Dir",
+		"Photos 2024/IMG 1.jpg",
+		"Photos 2024/Summer Trip/IMG 2.jpg",
+		"Photos 2024/Summer Trip",
+		"Photos 2024",
+		"Top File.txt",
+	}, relativePaths(t, root, paths))
+}
+
+func TestExpandGlobsSymlinks(t *testing.T) {
+	root := makeTree(t, "Tree/A File.txt", "Elsewhere/B File.txt")
+	require.NoError(t, os.Symlink(filepath.Join(root, "Elsewhere"), filepath.Join(root, "Tree", "Linked Dir")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "Tree"), filepath.Join(root, "Tree", "Loop")))
+
+	tree := filepath.Join(root, "Tree")
+	paths := expandGlobs([]string{tree}, walkOptions{Recursive: true})
+	assert.Equal(t, []string{"Tree/A File.txt"}, relativePaths(t, root, paths))
+
+	paths = expandGlobs([]string{tree}, walkOptions{Recursive: true, FollowSymlinks: true})
+	assert.Equal(t, []string{"Tree/A File.txt", "Tree/Linked Dir/B File.txt"}, relativePaths(t, root, paths))
+}
+
+func TestRecursiveRename(t *testing.T) {
+	root := makeTree(t, "Photos 2024/Summer Trip/IMG 2.jpg", "Photos 2024/IMG 1.jpg")
+
+	paths := expandGlobs([]string{filepath.Join(root, "*")}, walkOptions{Recursive: true, Dirs: true})
+	plan := planRenames(paths, defaultSlugOptions(), collisionFail)
+	require.False(t, hasProblems(plan))
+	applyPlan(os.Stdout, plan)
+
+	assert.FileExists(t, filepath.Join(root, "photos-2024", "summer-trip", "img-2.jpg"))
+	assert.FileExists(t, filepath.Join(root, "photos-2024", "img-1.jpg"))
+	assert.NoDirExists(t, filepath.Join(root, "Photos 2024"))
+}
+
+func TestPlanRenamesKeepsDirectoryNamesWhole(t *testing.T) {
+	root := makeTree(t, "Notes.2024 Draft/")
+
+	plan := planRenames([]string{filepath.Join(root, "Notes.2024 Draft")}, defaultSlugOptions(), collisionFail)
+	assert.Equal(t, filepath.Join(root, "notes-2024-draft"), plan[0].To)
+}